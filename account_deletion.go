@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/mvusic07/Chirpy/internal/database"
+)
+
+// purgeDeletedAccounts deletes accounts whose deletion grace period is over,
+// every interval until ctx is done. Everything they own goes with them
+// through ON DELETE CASCADE, except chirps others replied to or quoted,
+// which a trigger leaves behind as tombstones.
+func purgeDeletedAccounts(ctx context.Context, db *database.Queries, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		ids, err := db.DeleteScheduledUsers(ctx)
+		if err != nil {
+			log.Printf("Couldn't delete closed accounts: %s", err)
+			continue
+		}
+		for _, id := range ids {
+			log.Printf("Deleted closed account %s", id)
+		}
+	}
+}
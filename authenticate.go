@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/mvusic07/Chirpy/internal/auth"
+)
+
+// Scopes limit what a personal access token or an OAuth client may do.
+// Access tokens from a login session carry every scope. Public reads need no
+// token at all, so chirps:read only matters for endpoints that show a user's
+// own view.
+const (
+	scopeChirpsRead   = "chirps:read"
+	scopeChirpsWrite  = "chirps:write"
+	scopeProfileWrite = "profile:write"
+	scopeFollowsWrite = "follows:write"
+	scopeLikesWrite   = "likes:write"
+)
+
+var knownScopes = []string{scopeChirpsRead, scopeChirpsWrite, scopeProfileWrite, scopeFollowsWrite, scopeLikesWrite}
+
+// How a request authenticated.
+const (
+	authMethodSession             = "session"
+	authMethodPersonalAccessToken = "personal_access_token"
+	authMethodOAuth               = "oauth"
+)
+
+// principal is the user an authenticated request acts for, and how it
+// authenticated.
+type principal struct {
+	UserID uuid.UUID
+	Method string
+	// SessionID is the login session, or OAuth grant, the access token
+	// belongs to. It is unset for personal access tokens.
+	SessionID uuid.UUID
+	// PersonalAccessTokenID is set when the request used a personal access
+	// token.
+	PersonalAccessTokenID uuid.UUID
+	// ClientID is set when the request came from a third-party OAuth client.
+	ClientID uuid.UUID
+	// Scopes is nil for login sessions, which may do anything.
+	Scopes []string
+	// Role is the user's role as of when the access token was issued. It is
+	// only set for login sessions; delegated callers act as plain users.
+	Role string
+}
+
+// Delegated reports whether the request acts on the user's behalf with
+// limited scopes, through a personal access token or an OAuth client.
+func (p principal) Delegated() bool {
+	return p.Scopes != nil
+}
+
+func (p principal) HasScope(scope string) bool {
+	return !p.Delegated() || slices.Contains(p.Scopes, scope)
+}
+
+func (p principal) HasPermission(perm string) bool {
+	return !p.Delegated() && roleHasPermission(p.Role, perm)
+}
+
+// delegatedScopes copies scopes into a non-nil slice, so that a delegated
+// token with no scopes gets no access rather than full access.
+func delegatedScopes(scopes []string) []string {
+	return append([]string{}, scopes...)
+}
+
+var errInvalidPersonalAccessToken = errors.New("invalid or expired personal access token")
+
+// authenticate resolves the bearer token on r, which may be a first-party
+// access token, one issued to an OAuth client, or a personal access token.
+func (cfg *apiConfig) authenticate(r *http.Request) (principal, error) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return principal{}, err
+	}
+
+	if auth.IsPersonalAccessToken(token) {
+		pat, err := cfg.db.GetPersonalAccessToken(r.Context(), auth.HashToken(token))
+		if errors.Is(err, sql.ErrNoRows) {
+			return principal{}, errInvalidPersonalAccessToken
+		}
+		if err != nil {
+			return principal{}, err
+		}
+		if err := cfg.db.TouchPersonalAccessToken(r.Context(), pat.ID); err != nil {
+			log.Printf("Couldn't update personal access token last use: %s", err)
+		}
+		return principal{
+			UserID:                pat.UserID,
+			Method:                authMethodPersonalAccessToken,
+			PersonalAccessTokenID: pat.ID,
+			Scopes:                delegatedScopes(pat.Scopes),
+		}, nil
+	}
+
+	claims, err := cfg.keys.ParseJWT(token)
+	if err == nil {
+		userID, err := claims.UserID()
+		if err != nil {
+			return principal{}, err
+		}
+		return principal{
+			UserID:    userID,
+			Method:    authMethodSession,
+			SessionID: claims.SessionUUID(),
+			Role:      claims.Role,
+		}, nil
+	}
+	oauthClaims, oauthErr := cfg.keys.ParseOAuthJWT(token)
+	if oauthErr != nil {
+		return principal{}, err
+	}
+	userID, err := oauthClaims.UserID()
+	if err != nil {
+		return principal{}, err
+	}
+	return principal{
+		UserID:    userID,
+		Method:    authMethodOAuth,
+		SessionID: oauthClaims.SessionUUID(),
+		ClientID:  oauthClaims.ClientUUID(),
+		Scopes:    delegatedScopes(oauthClaims.Scopes()),
+	}, nil
+}
+
+type authMode int
+
+const (
+	// authModeOptional authenticates the request if it carries credentials.
+	authModeOptional authMode = iota
+	// authModeRequired rejects requests without valid credentials.
+	authModeRequired
+	// authModeForbidden is for endpoints that establish credentials, such
+	// as login and signup, and rejects requests that already carry some.
+	authModeForbidden
+)
+
+// authPolicy is what a route requires of the caller.
+type authPolicy struct {
+	mode authMode
+	// scope is required of delegated callers.
+	scope string
+	// sessionOnly turns away delegated callers altogether, for endpoints
+	// that manage the account's credentials.
+	sessionOnly bool
+	// permission is required of the caller's role.
+	permission string
+}
+
+var (
+	authOptional  = authPolicy{mode: authModeOptional}
+	authForbidden = authPolicy{mode: authModeForbidden}
+	authSession   = authPolicy{mode: authModeRequired, sessionOnly: true}
+)
+
+// authRequired requires a login session, or a delegated caller granted scope.
+func authRequired(scope string) authPolicy {
+	return authPolicy{mode: authModeRequired, scope: scope}
+}
+
+// authPermission requires a login session whose role grants perm.
+func authPermission(perm string) authPolicy {
+	return authPolicy{mode: authModeRequired, sessionOnly: true, permission: perm}
+}
+
+type principalContextKey struct{}
+
+// requestPrincipal returns the caller resolved by withAuth, and false for
+// anonymous requests.
+func requestPrincipal(r *http.Request) (principal, bool) {
+	p, ok := r.Context().Value(principalContextKey{}).(principal)
+	return p, ok
+}
+
+// withAuth enforces policy before calling next, making the caller available
+// to it through requestPrincipal.
+func (cfg *apiConfig) withAuth(policy authPolicy, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hasCredentials := r.Header.Get("Authorization") != ""
+		if policy.mode == authModeForbidden {
+			if hasCredentials {
+				respondWithError(w, http.StatusBadRequest, "This endpoint doesn't accept an Authorization header", nil)
+				return
+			}
+			next(w, r)
+			return
+		}
+		if !hasCredentials {
+			if policy.mode == authModeRequired {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="chirpy"`)
+				respondWithError(w, http.StatusUnauthorized, "Authentication required", nil)
+				return
+			}
+			next(w, r)
+			return
+		}
+
+		// Bad credentials are rejected even where they are optional, rather
+		// than silently serving the request as anonymous.
+		p, err := cfg.authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="chirpy", error="invalid_token"`)
+			respondWithError(w, http.StatusUnauthorized, "Couldn't validate access token", err)
+			return
+		}
+		if policy.sessionOnly && p.Delegated() {
+			respondWithError(w, http.StatusForbidden, "This endpoint requires a login session", nil)
+			return
+		}
+		if policy.scope != "" && !p.HasScope(policy.scope) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="chirpy", error="insufficient_scope", scope="`+policy.scope+`"`)
+			respondWithError(w, http.StatusForbidden, "Token is missing the "+policy.scope+" scope", nil)
+			return
+		}
+		if policy.permission != "" && !p.HasPermission(policy.permission) {
+			respondWithError(w, http.StatusForbidden, "You don't have permission to do that", nil)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), principalContextKey{}, p)))
+	})
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mvusic07/Chirpy/internal/auth"
+)
+
+func TestWithAuth(t *testing.T) {
+	keys, err := auth.LoadKeySet(t.TempDir())
+	if err != nil {
+		t.Fatalf("LoadKeySet failed: %v", err)
+	}
+	cfg := &apiConfig{keys: keys}
+
+	userID := uuid.New()
+	sessionToken, err := keys.MakeJWT(userID, uuid.New(), time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+	moderatorToken, err := keys.MakeRoleJWT(userID, uuid.New(), roleModerator, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeRoleJWT failed: %v", err)
+	}
+	adminToken, err := keys.MakeRoleJWT(userID, uuid.New(), roleAdmin, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeRoleJWT failed: %v", err)
+	}
+	oauthToken, err := keys.MakeOAuthJWT(userID, uuid.New(), uuid.New(), []string{scopeChirpsRead}, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeOAuthJWT failed: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		policy     authPolicy
+		header     string
+		wantStatus int
+		wantMethod string
+	}{
+		{name: "optional anonymous", policy: authOptional, wantStatus: http.StatusOK},
+		{name: "optional with session", policy: authOptional, header: "Bearer " + sessionToken, wantStatus: http.StatusOK, wantMethod: authMethodSession},
+		{name: "optional with bad token", policy: authOptional, header: "Bearer nope", wantStatus: http.StatusUnauthorized},
+		{name: "required anonymous", policy: authRequired(scopeChirpsWrite), wantStatus: http.StatusUnauthorized},
+		{name: "required without bearer scheme", policy: authRequired(scopeChirpsWrite), header: sessionToken, wantStatus: http.StatusUnauthorized},
+		{name: "required with session", policy: authRequired(scopeChirpsWrite), header: "Bearer " + sessionToken, wantStatus: http.StatusOK, wantMethod: authMethodSession},
+		{name: "required with scope", policy: authRequired(scopeChirpsRead), header: "Bearer " + oauthToken, wantStatus: http.StatusOK, wantMethod: authMethodOAuth},
+		{name: "required missing scope", policy: authRequired(scopeChirpsWrite), header: "Bearer " + oauthToken, wantStatus: http.StatusForbidden},
+		{name: "session only rejects delegated", policy: authSession, header: "Bearer " + oauthToken, wantStatus: http.StatusForbidden},
+		{name: "session only with session", policy: authSession, header: "Bearer " + sessionToken, wantStatus: http.StatusOK, wantMethod: authMethodSession},
+		{name: "permission anonymous", policy: authPermission(permModerateUsers), wantStatus: http.StatusUnauthorized},
+		{name: "permission without role", policy: authPermission(permModerateUsers), header: "Bearer " + sessionToken, wantStatus: http.StatusForbidden},
+		{name: "permission granted", policy: authPermission(permModerateUsers), header: "Bearer " + moderatorToken, wantStatus: http.StatusOK, wantMethod: authMethodSession},
+		{name: "permission not granted", policy: authPermission(permReadMetrics), header: "Bearer " + moderatorToken, wantStatus: http.StatusForbidden},
+		{name: "permission for admin", policy: authPermission(permReadMetrics), header: "Bearer " + adminToken, wantStatus: http.StatusOK, wantMethod: authMethodSession},
+		{name: "permission rejects delegated", policy: authPermission(permModerateUsers), header: "Bearer " + oauthToken, wantStatus: http.StatusForbidden},
+		{name: "forbidden anonymous", policy: authForbidden, wantStatus: http.StatusOK},
+		{name: "forbidden with credentials", policy: authForbidden, header: "Bearer " + sessionToken, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotMethod string
+			handler := cfg.withAuth(tt.policy, func(w http.ResponseWriter, r *http.Request) {
+				if p, ok := requestPrincipal(r); ok {
+					if p.UserID != userID {
+						t.Errorf("Expected user %s, got %s", userID, p.UserID)
+					}
+					gotMethod = p.Method
+				}
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if gotMethod != tt.wantMethod {
+				t.Errorf("Expected method %q, got %q", tt.wantMethod, gotMethod)
+			}
+		})
+	}
+}
+
+func TestOutranks(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{roleAdmin, roleModerator, true},
+		{roleModerator, roleUser, true},
+		{roleModerator, roleModerator, false},
+		{roleModerator, roleAdmin, false},
+		{roleUser, roleUser, false},
+		{"", roleUser, false},
+	}
+	for _, tt := range tests {
+		if got := outranks(tt.a, tt.b); got != tt.want {
+			t.Errorf("outranks(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mvusic07/Chirpy/internal/auth"
+	"github.com/mvusic07/Chirpy/internal/database"
+)
+
+const cliUsage = `usage: chirpy [command]
+
+With no command, chirpy runs the server.
+
+Commands:
+  set-role <email> <role>   set a user's role (user, moderator or admin)
+  normalize-emails          rewrite stored emails in normalized form,
+                            reporting any that collide or are invalid`
+
+// runCommand runs a maintenance command given on the command line, such as
+// set-role, which creates the first admin.
+func runCommand(ctx context.Context, dbURL string, args []string) error {
+	dbConn, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer dbConn.Close()
+	db := database.New(dbConn)
+
+	switch args[0] {
+	case "set-role":
+		if len(args) != 3 {
+			return errors.New(cliUsage)
+		}
+		return setRole(ctx, db, args[1], args[2])
+	case "normalize-emails":
+		if len(args) != 1 {
+			return errors.New(cliUsage)
+		}
+		return normalizeEmails(ctx, db)
+	default:
+		return errors.New(cliUsage)
+	}
+}
+
+func setRole(ctx context.Context, db *database.Queries, email, role string) error {
+	if !slices.Contains(knownRoles, role) {
+		return fmt.Errorf("unknown role %q, want one of %s", role, strings.Join(knownRoles, ", "))
+	}
+	normalized, err := auth.NormalizeEmail(email)
+	if err != nil {
+		return fmt.Errorf("invalid email %s", email)
+	}
+	user, err := db.GetUserByEmail(ctx, normalized)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("no user with email %s", email)
+	}
+	if err != nil {
+		return err
+	}
+	user, err = db.SetUserRole(ctx, database.SetUserRoleParams{
+		ID:   user.ID,
+		Role: role,
+	})
+	if err != nil {
+		return err
+	}
+	// Access tokens carry the old role until they expire otherwise.
+	if err := newRevocationStore(db).RevokeUser(ctx, user.ID); err != nil {
+		return fmt.Errorf("revoking access tokens: %w", err)
+	}
+	fmt.Printf("%s is now %s %s\n", user.Email, article(user.Role), user.Role)
+	return nil
+}
+
+// normalizeEmails brings emails stored before normalization into line. The
+// migration that made emails unique ignoring case stops on accounts that
+// differ only in case; this also finds those that differ in Unicode form or
+// IDNA encoding, which can't be detected in SQL. Colliding and invalid
+// emails are reported and left alone for an operator to resolve.
+func normalizeEmails(ctx context.Context, db *database.Queries) error {
+	users, err := db.ListUserEmails(ctx)
+	if err != nil {
+		return err
+	}
+
+	byEmail := map[string][]database.ListUserEmailsRow{}
+	var emails []string
+	for _, user := range users {
+		normalized, err := auth.NormalizeEmail(user.Email)
+		if err != nil {
+			fmt.Printf("invalid: %s <%s>\n", user.ID, user.Email)
+			continue
+		}
+		if _, ok := byEmail[normalized]; !ok {
+			emails = append(emails, normalized)
+		}
+		byEmail[normalized] = append(byEmail[normalized], user)
+	}
+
+	updated, collisions := 0, 0
+	for _, email := range emails {
+		accounts := byEmail[email]
+		if len(accounts) > 1 {
+			collisions++
+			fmt.Printf("collision: %s is shared by", email)
+			for _, user := range accounts {
+				fmt.Printf(" %s <%s>", user.ID, user.Email)
+			}
+			fmt.Println()
+			continue
+		}
+		if accounts[0].Email == email {
+			continue
+		}
+		err := db.SetUserEmail(ctx, database.SetUserEmailParams{
+			ID:    accounts[0].ID,
+			Email: email,
+		})
+		if err != nil {
+			return fmt.Errorf("updating %s: %w", accounts[0].ID, err)
+		}
+		updated++
+	}
+
+	fmt.Printf("Normalized %d emails, %d collisions\n", updated, collisions)
+	if collisions > 0 {
+		return errors.New("some emails collide; merge or rename those accounts and run again")
+	}
+	return nil
+}
+
+func article(word string) string {
+	if strings.ContainsRune("aeiou", rune(word[0])) {
+		return "an"
+	}
+	return "a"
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mvusic07/Chirpy/internal/auth"
+	"github.com/mvusic07/Chirpy/internal/database"
+	"github.com/mvusic07/Chirpy/internal/mailer"
+)
+
+const emailVerificationTokenTTL = time.Hour * 24
+
+// startEmailVerification replaces any outstanding verification link for the
+// user with a new one for email and mails it there.
+func (cfg *apiConfig) startEmailVerification(ctx context.Context, userID uuid.UUID, email string) error {
+	msg, err := cfg.newEmailVerification(ctx, cfg.db, userID, email)
+	if err != nil {
+		return err
+	}
+	cfg.sendEmail(msg)
+	return nil
+}
+
+// newEmailVerification is startEmailVerification without the sending: it
+// returns the message instead, so a caller writing through a transaction can
+// hold it until the link's token is committed.
+func (cfg *apiConfig) newEmailVerification(ctx context.Context, db *database.Queries, userID uuid.UUID, email string) (mailer.Message, error) {
+	err := db.InvalidateEmailVerificationTokens(ctx, userID)
+	if err != nil {
+		return mailer.Message{}, err
+	}
+	token := auth.MakeToken()
+	_, err = db.CreateEmailVerificationToken(ctx, database.CreateEmailVerificationTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    userID,
+		Email:     email,
+		ExpiresAt: time.Now().UTC().Add(emailVerificationTokenTTL),
+	})
+	if err != nil {
+		return mailer.Message{}, err
+	}
+
+	link := cfg.baseURL + "/api/verify-email?token=" + url.QueryEscape(token)
+	return mailer.Message{
+		To:      email,
+		Subject: "Confirm your email for Chirpy",
+		Body: fmt.Sprintf("Please confirm that this is your email address by opening this link "+
+			"within the next 24 hours:\n\n%s\n\n"+
+			"If you didn't sign up for Chirpy or change your email, you can ignore this email.\n", link),
+	}, nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/lib/pq"
+	"github.com/mvusic07/Chirpy/internal/auth"
+	"github.com/mvusic07/Chirpy/internal/database"
+)
+
+// normalizeEmailParam normalizes an email taken from a request, responding
+// with a validation error if it isn't a usable address.
+func normalizeEmailParam(w http.ResponseWriter, email string) (string, bool) {
+	normalized, err := auth.NormalizeEmail(email)
+	if err != nil {
+		respondWithValidationErrors(w, "Invalid email", []fieldError{{
+			Field:   "email",
+			Code:    "invalid",
+			Message: "Email must be an address like name@example.com",
+		}})
+		return "", false
+	}
+	return normalized, true
+}
+
+// getUserByEmail looks up an account by an email as typed by a user. An
+// invalid email is reported as sql.ErrNoRows, like any other that isn't
+// registered.
+func (cfg *apiConfig) getUserByEmail(ctx context.Context, email string) (database.User, error) {
+	normalized, err := auth.NormalizeEmail(email)
+	if err != nil {
+		return database.User{}, sql.ErrNoRows
+	}
+	return cfg.db.GetUserByEmail(ctx, normalized)
+}
+
+// isUniqueViolation reports whether err is Postgres refusing a duplicate
+// value, such as an email already registered to another account. Given a
+// constraint or index name, it only matches violations of that one.
+func isUniqueViolation(err error, constraint ...string) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != "23505" {
+		return false
+	}
+	return len(constraint) == 0 || slices.Contains(constraint, pqErr.Constraint)
+}
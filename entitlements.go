@@ -0,0 +1,46 @@
+package main
+
+import "github.com/mvusic07/Chirpy/internal/database"
+
+// Membership tiers.
+const (
+	tierFree      = "free"
+	tierChirpyRed = "chirpy_red"
+)
+
+// entitlements are the limits a user's tier puts on them. Handlers look them
+// up with entitlementsFor rather than hard-coding limits, so changing what a
+// tier gets only means changing tierEntitlements. Only features Chirpy has
+// are listed; editing, scheduling or media get their limits here when they
+// are built, so clients are never promised something that isn't there.
+type entitlements struct {
+	Tier string
+	// MaxChirpLength is in bytes.
+	MaxChirpLength int
+	// ChirpsPerHour is the posting budget over a sliding hour.
+	ChirpsPerHour int
+}
+
+var tierEntitlements = map[string]entitlements{
+	tierFree: {
+		Tier:           tierFree,
+		MaxChirpLength: 140,
+		ChirpsPerHour:  50,
+	},
+	tierChirpyRed: {
+		Tier:           tierChirpyRed,
+		MaxChirpLength: 500,
+		ChirpsPerHour:  300,
+	},
+}
+
+func userTier(u database.User) string {
+	if isChirpyRed(u) {
+		return tierChirpyRed
+	}
+	return tierFree
+}
+
+func entitlementsFor(u database.User) entitlements {
+	return tierEntitlements[userTier(u)]
+}
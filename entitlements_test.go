@@ -0,0 +1,140 @@
+package main
+
+import (
+	"database/sql"
+	"maps"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mvusic07/Chirpy/internal/database"
+)
+
+func TestEntitlementsFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     database.User
+		wantTier string
+	}{
+		{name: "free", user: database.User{}, wantTier: tierFree},
+		{name: "red without end", user: database.User{IsChirpyRed: true}, wantTier: tierChirpyRed},
+		{name: "red in period", user: database.User{IsChirpyRed: true, ChirpyRedUntil: sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true}}, wantTier: tierChirpyRed},
+		{name: "red lapsed", user: database.User{IsChirpyRed: true, ChirpyRedUntil: sql.NullTime{Time: time.Now().Add(-time.Hour), Valid: true}}, wantTier: tierFree},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := entitlementsFor(tt.user)
+			if e.Tier != tt.wantTier {
+				t.Errorf("Expected tier %s, got %s", tt.wantTier, e.Tier)
+			}
+			if e.MaxChirpLength == 0 || e.ChirpsPerHour == 0 {
+				t.Errorf("Expected every tier to be able to post, got %+v", e)
+			}
+		})
+	}
+}
+
+func TestValidateChirpLength(t *testing.T) {
+	free := tierEntitlements[tierFree].MaxChirpLength
+	red := tierEntitlements[tierChirpyRed].MaxChirpLength
+	body := strings.Repeat("a", free+1)
+
+	if _, err := validateChirp(body, free); err == nil {
+		t.Error("Expected an over-long chirp to be rejected on the free tier")
+	}
+	if _, err := validateChirp(body, red); err != nil {
+		t.Errorf("Expected the chirp to fit on Chirpy Red, got %v", err)
+	}
+}
+
+func TestChirpBudget_DeletingDoesNotRefund(t *testing.T) {
+	s := newTestServer(t)
+	session := s.signup(t, "saul@example.com")
+	other := s.signup(t, "kim@example.com")
+	user, err := s.db.GetUserByID(t.Context(), session.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID failed: %v", err)
+	}
+	// Use up all but one slot.
+	for range entitlementsFor(user).ChirpsPerHour - 1 {
+		if _, err := s.conn.Exec("INSERT INTO chirp_posts (user_id, created_at) VALUES ($1, NOW())", user.ID); err != nil {
+			t.Fatalf("Couldn't record post: %v", err)
+		}
+	}
+
+	var chirp Chirp
+	decode(t, s.do(t, "POST", "/api/chirps", session.Token, map[string]string{"body": "last one"}), http.StatusCreated, &chirp)
+	decode(t, s.do(t, "DELETE", "/api/chirps/"+chirp.ID.String(), session.Token, nil), http.StatusNoContent, nil)
+
+	rec := s.do(t, "POST", "/api/chirps", session.Token, map[string]string{"body": "one more"})
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected deleting a chirp not to free up its slot, got %d: %s", rec.Code, rec.Body)
+	}
+
+	// Rechirps don't count against the budget, even when it's used up.
+	var shared Chirp
+	decode(t, s.do(t, "POST", "/api/chirps", other.Token, map[string]string{"body": "share me"}), http.StatusCreated, &shared)
+	decode(t, s.do(t, "POST", "/api/chirps/"+shared.ID.String()+"/rechirp", session.Token, nil), http.StatusCreated, nil)
+	stats, err := s.db.GetChirpRateWindow(t.Context(), database.GetChirpRateWindowParams{
+		UserID: user.ID,
+		Since:  time.Now().UTC().Add(-chirpRateWindow),
+	})
+	if err != nil {
+		t.Fatalf("GetChirpRateWindow failed: %v", err)
+	}
+	if int(stats.Count) != entitlementsFor(user).ChirpsPerHour {
+		t.Errorf("Expected the rechirp not to be counted, got %d posts", stats.Count)
+	}
+}
+
+func TestChirpBudget_ConcurrentPostsShareLastSlot(t *testing.T) {
+	s := newTestServer(t)
+	session := s.signup(t, "chuck@example.com")
+	user, err := s.db.GetUserByID(t.Context(), session.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID failed: %v", err)
+	}
+	for range entitlementsFor(user).ChirpsPerHour - 1 {
+		if _, err := s.conn.Exec("INSERT INTO chirp_posts (user_id, created_at) VALUES ($1, NOW())", user.ID); err != nil {
+			t.Fatalf("Couldn't record post: %v", err)
+		}
+	}
+
+	var created, throttled atomic.Int32
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			rec := s.do(t, "POST", "/api/chirps", session.Token, map[string]string{"body": "me too"})
+			switch rec.Code {
+			case http.StatusCreated:
+				created.Add(1)
+			case http.StatusTooManyRequests:
+				throttled.Add(1)
+			}
+		})
+	}
+	wg.Wait()
+
+	if created.Load() != 1 || throttled.Load() != 9 {
+		t.Errorf("Expected exactly one post to fit in the last slot, got %d created and %d throttled", created.Load(), throttled.Load())
+	}
+}
+
+func TestHandlerEntitlements(t *testing.T) {
+	s := newTestServer(t)
+	session := s.signup(t, "huell@example.com")
+
+	var got struct {
+		Tier   string         `json:"tier"`
+		Limits map[string]int `json:"limits"`
+	}
+	decode(t, s.do(t, "GET", "/api/me/entitlements", session.Token, nil), http.StatusOK, &got)
+	free := tierEntitlements[tierFree]
+	want := map[string]int{"chirp_length": free.MaxChirpLength, "chirps_per_hour": free.ChirpsPerHour}
+	if got.Tier != tierFree || !maps.Equal(got.Limits, want) {
+		t.Errorf("Expected %s with %v, got %s with %v", tierFree, want, got.Tier, got.Limits)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mvusic07/Chirpy/internal/database"
+)
+
+const (
+	// dataExportTTL is how long a finished archive stays downloadable.
+	dataExportTTL = 7 * 24 * time.Hour
+	// dataExportStaleAfter is how long a running export may go untouched
+	// before another worker assumes it crashed and takes it over.
+	dataExportStaleAfter = 10 * time.Minute
+)
+
+// dataExporter builds the archives requested through POST /api/users/export
+// in the background. Jobs are queued in the data_exports table, so several
+// servers can share the work and a restart loses nothing.
+type dataExporter struct {
+	db   *database.Queries
+	wake chan struct{}
+}
+
+func newDataExporter(db *database.Queries) *dataExporter {
+	return &dataExporter{
+		db:   db,
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// Notify tells the exporter a job is waiting, so it needn't wait for the
+// next poll.
+func (e *dataExporter) Notify() {
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run works through queued exports whenever notified, and every interval,
+// until ctx is done.
+func (e *dataExporter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := e.db.DeleteExpiredDataExports(ctx); err != nil {
+				log.Printf("Couldn't delete expired data exports: %s", err)
+			}
+		case <-e.wake:
+		}
+
+		for {
+			job, err := e.db.ClaimDataExport(ctx, time.Now().UTC().Add(-dataExportStaleAfter))
+			if errors.Is(err, sql.ErrNoRows) {
+				break
+			}
+			if err != nil {
+				log.Printf("Couldn't claim data export: %s", err)
+				break
+			}
+			e.export(ctx, job.ID, job.UserID)
+		}
+	}
+}
+
+func (e *dataExporter) export(ctx context.Context, exportID, userID uuid.UUID) {
+	archive, err := e.buildArchive(ctx, userID)
+	if err != nil {
+		log.Printf("Data export %s failed: %s", exportID, err)
+		err = e.db.FailDataExport(ctx, database.FailDataExportParams{
+			ID:    exportID,
+			Error: sql.NullString{String: "Couldn't build the archive", Valid: true},
+		})
+		if err != nil {
+			log.Printf("Couldn't mark data export %s failed: %s", exportID, err)
+		}
+		return
+	}
+
+	err = e.db.CompleteDataExport(ctx, database.CompleteDataExportParams{
+		ID:        exportID,
+		Archive:   archive,
+		ExpiresAt: sql.NullTime{Time: time.Now().UTC().Add(dataExportTTL), Valid: true},
+	})
+	if err != nil {
+		log.Printf("Couldn't save data export %s: %s", exportID, err)
+	}
+}
+
+func (e *dataExporter) buildArchive(ctx context.Context, userID uuid.UUID) ([]byte, error) {
+	user, err := e.db.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	chirps, err := e.db.ListAllChirpsByAuthor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return buildExportArchive(user, chirps)
+}
+
+// buildExportArchive returns a zip of the user's profile and chirps as JSON.
+// Chirps can't carry media yet; when they can, it belongs under media/.
+func buildExportArchive(user database.User, dbChirps []database.Chirp) ([]byte, error) {
+	chirps := newChirps(dbChirps)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", newUser(user)},
+		{"chirps.json", chirps},
+	}
+	for _, file := range files {
+		f, err := zw.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(file.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
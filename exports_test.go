@@ -0,0 +1,61 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mvusic07/Chirpy/internal/database"
+)
+
+func TestBuildExportArchive(t *testing.T) {
+	user := database.User{ID: uuid.New(), Email: "walt@breakingbad.com", Role: roleUser}
+	dbChirps := []database.Chirp{
+		{ID: uuid.New(), CreatedAt: time.Now(), UserID: uuid.NullUUID{UUID: user.ID, Valid: true}, Body: "first"},
+		{ID: uuid.New(), CreatedAt: time.Now(), UserID: uuid.NullUUID{UUID: user.ID, Valid: true}, Body: "second"},
+	}
+
+	archive, err := buildExportArchive(user, dbChirps)
+	if err != nil {
+		t.Fatalf("buildExportArchive failed: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatalf("Couldn't open archive: %v", err)
+	}
+
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var profile User
+	readJSON(t, files["profile.json"], &profile)
+	if profile.ID != user.ID || profile.Email != user.Email {
+		t.Errorf("Expected profile of %s, got %+v", user.Email, profile)
+	}
+
+	var chirps []Chirp
+	readJSON(t, files["chirps.json"], &chirps)
+	if len(chirps) != 2 || chirps[0].Body != "first" || chirps[1].Body != "second" {
+		t.Errorf("Expected both chirps in order, got %+v", chirps)
+	}
+}
+
+func readJSON(t *testing.T, f *zip.File, v any) {
+	t.Helper()
+	if f == nil {
+		t.Fatal("Missing file in archive")
+	}
+	rc, err := f.Open()
+	if err != nil {
+		t.Fatalf("Couldn't open %s: %v", f.Name, err)
+	}
+	defer rc.Close()
+	if err := json.NewDecoder(rc).Decode(v); err != nil {
+		t.Fatalf("Couldn't decode %s: %v", f.Name, err)
+	}
+}
@@ -10,4 +10,10 @@ require (
 
 require golang.org/x/crypto v0.41.0
 
-require github.com/golang-jwt/jwt/v5 v5.3.0 
+require (
+	github.com/golang-jwt/jwt/v5 v5.3.0
+	golang.org/x/net v0.43.0
+	golang.org/x/text v0.28.0
+)
+
+require golang.org/x/sys v0.35.0 // indirect
@@ -1,14 +1,17 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
+	"log"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/mvusic07/Chirpy/internal/auth"
 	"github.com/mvusic07/Chirpy/internal/database"
 )
 
@@ -16,61 +19,278 @@ type Chirp struct {
 	ID        uuid.UUID `json:"id"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
-	UserID    uuid.UUID `json:"user_id"`
-	Body      string    `json:"body"`
+	// UserID is null on a tombstone whose author's account was deleted.
+	UserID         *uuid.UUID `json:"user_id"`
+	Body           string     `json:"body"`
+	InReplyToID    *uuid.UUID `json:"in_reply_to_id"`
+	ConversationID uuid.UUID  `json:"conversation_id"`
+	RechirpOfID    *uuid.UUID `json:"rechirp_of_id"`
+	QuoteOfID      *uuid.UUID `json:"quote_of_id"`
+	ReplyCount     int        `json:"reply_count"`
+	LikeCount      int        `json:"like_count"`
+	RechirpCount   int        `json:"rechirp_count"`
+	QuoteCount     int        `json:"quote_count"`
+	// LikedByMe is only ever true for a caller who can read chirps as
+	// themselves; see attachLikedByMe.
+	LikedByMe bool `json:"liked_by_me"`
+	// Deleted marks a tombstone: a deleted chirp kept, without its body, so
+	// its replies stay in the thread.
+	Deleted bool `json:"deleted"`
+	// Author is embedded on request, with ?expand=author.
+	Author *AuthorSummary `json:"author,omitempty"`
+	// Original is the chirp a rechirp or quote shares.
+	Original *SharedChirp `json:"original,omitempty"`
 }
 
-func (cfg *apiConfig) handlerChirpsCreate(w http.ResponseWriter, r *http.Request) {
-	type parameters struct {
-		Body string `json:"body"`
+func newChirp(c database.Chirp) Chirp {
+	chirp := Chirp{
+		ID:             c.ID,
+		CreatedAt:      c.CreatedAt,
+		UpdatedAt:      c.UpdatedAt,
+		Body:           c.Body,
+		ConversationID: c.ConversationID,
+		ReplyCount:     int(c.ReplyCount),
+		LikeCount:      int(c.LikeCount),
+		RechirpCount:   int(c.RechirpCount),
+		QuoteCount:     int(c.QuoteCount),
+		Deleted:        c.DeletedAt.Valid,
+	}
+	if c.UserID.Valid {
+		chirp.UserID = &c.UserID.UUID
+	}
+	if c.InReplyToID.Valid {
+		chirp.InReplyToID = &c.InReplyToID.UUID
+	}
+	if c.RechirpOfID.Valid {
+		chirp.RechirpOfID = &c.RechirpOfID.UUID
 	}
+	if c.QuoteOfID.Valid {
+		chirp.QuoteOfID = &c.QuoteOfID.UUID
+	}
+	return chirp
+}
 
-	tokenString, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Missing or invalid Authorization header", err)
-		return
+func newChirps(dbChirps []database.Chirp) []Chirp {
+	chirps := []Chirp{}
+	for _, dbChirp := range dbChirps {
+		chirps = append(chirps, newChirp(dbChirp))
 	}
+	return chirps
+}
 
-	userID, err := auth.ValidateJWT(tokenString, cfg.tokenSecret)
+// decorateChirps fills in what chirps show beyond their rows: the chirps they
+// share, their authors if asked for with ?expand=author, and whether the
+// caller liked them.
+func (cfg *apiConfig) decorateChirps(r *http.Request, chirps []Chirp) error {
+	shared, err := cfg.sharedChirps(r.Context(), chirps)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Invalid token", err)
+		return err
+	}
+	all := append(slices.Clone(chirps), shared...)
+	if wantsExpansion(r.URL.Query(), "author") {
+		if err := cfg.attachAuthors(r.Context(), all); err != nil {
+			return err
+		}
+	}
+	if err := cfg.attachLikedByMe(r, all); err != nil {
+		return err
+	}
+	copy(chirps, all)
+	attachShared(chirps, all[len(chirps):])
+	return nil
+}
+
+func (cfg *apiConfig) handlerChirpsCreate(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body        string     `json:"body"`
+		InReplyToID *uuid.UUID `json:"in_reply_to_id"`
+		QuoteOfID   *uuid.UUID `json:"quote_of_id"`
+	}
+
+	user, limits, ok := cfg.postingUser(w, r)
+	if !ok {
 		return
 	}
+	userID := user.ID
 
 	decoder := json.NewDecoder(r.Body)
 	params := parameters{}
-	err = decoder.Decode(&params)
+	err := decoder.Decode(&params)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
 		return
 	}
 
-	cleaned, err := validateChirp(params.Body)
+	cleaned, err := validateChirp(params.Body, limits.MaxChirpLength)
 	if err != nil {
 		respondWithError(w, http.StatusBadRequest, err.Error(), err)
 		return
 	}
 
-	chirp, err := cfg.db.CreateChirp(r.Context(), database.CreateChirpParams{
-		Body:   cleaned,
-		UserID: userID,
-	})
+	quoteOfID := uuid.NullUUID{}
+	if params.QuoteOfID != nil {
+		if strings.TrimSpace(cleaned) == "" {
+			respondWithError(w, http.StatusBadRequest, "A quote needs a body; rechirp to share a chirp as is", nil)
+			return
+		}
+		quoted, err := cfg.shareTarget(r.Context(), *params.QuoteOfID)
+		if errors.Is(err, errSharedNotFound) {
+			respondWithError(w, http.StatusNotFound, "The chirp you are quoting doesn't exist", err)
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't find the quoted chirp", err)
+			return
+		}
+		quoteOfID = uuid.NullUUID{UUID: quoted.ID, Valid: true}
+	}
+
+	chirp, err := cfg.createChirp(r.Context(), database.CreateChirpParams{
+		Body:        cleaned,
+		UserID:      userID,
+		InReplyToID: nullUUID(params.InReplyToID),
+		QuoteOfID:   quoteOfID,
+	}, limits)
+	var overBudget *chirpBudgetError
+	if errors.As(err, &overBudget) {
+		respondWithRetryAfter(w, overBudget.retryAfter)
+		return
+	}
+	if errors.Is(err, errParentNotFound) {
+		respondWithError(w, http.StatusNotFound, "The chirp you are replying to doesn't exist", err)
+		return
+	}
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Couldn't create chirp", err)
 		return
 	}
+	cfg.chirpPosted(r.Context(), chirp)
+
+	chirps := []Chirp{newChirp(chirp)}
+	if err := cfg.decorateChirps(r, chirps); err != nil {
+		log.Printf("Couldn't decorate new chirp %s: %s", chirp.ID, err)
+	}
+	respondWithJSON(w, http.StatusCreated, chirps[0])
+}
 
-	respondWithJSON(w, http.StatusCreated, Chirp{
-		ID:        chirp.ID,
+// postingUser loads the caller and checks that they may post chirps at all,
+// responding with an error if not.
+func (cfg *apiConfig) postingUser(w http.ResponseWriter, r *http.Request) (database.User, entitlements, bool) {
+	caller, _ := requestPrincipal(r)
+	user, err := cfg.db.GetUserByID(r.Context(), caller.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't find user", err)
+		return database.User{}, entitlements{}, false
+	}
+	if cfg.requireVerifiedEmail && !user.EmailVerifiedAt.Valid {
+		respondWithError(w, http.StatusForbidden, "Verify your email before posting chirps", nil)
+		return database.User{}, entitlements{}, false
+	}
+	return user, entitlementsFor(user), true
+}
+
+var errParentNotFound = errors.New("parent chirp not found")
+
+// chirpBudgetError means the author has used up their hourly posting budget.
+type chirpBudgetError struct {
+	retryAfter time.Duration
+}
+
+func (e *chirpBudgetError) Error() string {
+	return "chirp posting budget used up"
+}
+
+// createChirp inserts a chirp and records it against the author's posting
+// budget, returning a *chirpBudgetError if it's used up. The author's row is
+// locked while the budget is counted, so concurrent posts can't all squeeze
+// into the last slot. A reply joins its parent's conversation, whose reply count the
+// database keeps; the parent row is locked meanwhile so it can't be deleted
+// out from under the reply. Replying to a rechirp replies to the chirp it shares.
+func (cfg *apiConfig) createChirp(ctx context.Context, params database.CreateChirpParams, limits entitlements) (database.Chirp, error) {
+	tx, err := cfg.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	if _, err := qtx.GetUserForUpdate(ctx, params.UserID); err != nil {
+		return database.Chirp{}, err
+	}
+	retryAfter, err := chirpRetryAfter(ctx, qtx, params.UserID, limits)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	if retryAfter > 0 {
+		return database.Chirp{}, &chirpBudgetError{retryAfter: retryAfter}
+	}
+
+	var parent database.Chirp
+	if params.InReplyToID.Valid {
+		parent, err = qtx.GetChirpForUpdate(ctx, params.InReplyToID.UUID)
+		if err == nil && parent.RechirpOfID.Valid {
+			parent, err = qtx.GetChirpForUpdate(ctx, parent.RechirpOfID.UUID)
+		}
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && parent.DeletedAt.Valid) {
+			return database.Chirp{}, errParentNotFound
+		}
+		if err != nil {
+			return database.Chirp{}, err
+		}
+		params.InReplyToID = uuid.NullUUID{UUID: parent.ID, Valid: true}
+		params.ConversationID = uuid.NullUUID{UUID: parent.ConversationID, Valid: true}
+	}
+	chirp, err := qtx.CreateChirp(ctx, params)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+
+	err = qtx.RecordChirpPost(ctx, database.RecordChirpPostParams{
+		UserID:    params.UserID,
 		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserID:    chirp.UserID,
 	})
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	err = qtx.PruneChirpPosts(ctx, database.PruneChirpPostsParams{
+		UserID: params.UserID,
+		Before: chirp.CreatedAt.Add(-chirpRateWindow),
+	})
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	return chirp, tx.Commit()
+}
+
+func nullUUID(id *uuid.UUID) uuid.NullUUID {
+	if id == nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: *id, Valid: true}
+}
+
+// chirpRateWindow is the sliding window the posting budget applies to.
+const chirpRateWindow = time.Hour
+
+// chirpRetryAfter returns how long the user must wait before posting again,
+// or zero if they are within their hourly budget. It counts the posts
+// recorded by createChirp, so deleting a chirp doesn't free up a slot.
+func chirpRetryAfter(ctx context.Context, db *database.Queries, userID uuid.UUID, limits entitlements) (time.Duration, error) {
+	stats, err := db.GetChirpRateWindow(ctx, database.GetChirpRateWindowParams{
+		UserID: userID,
+		Since:  time.Now().UTC().Add(-chirpRateWindow),
+	})
+	if err != nil {
+		return 0, err
+	}
+	if int(stats.Count) < limits.ChirpsPerHour {
+		return 0, nil
+	}
+	// A slot frees up when the oldest post in the window falls out of it.
+	return max(time.Until(stats.Oldest.Add(chirpRateWindow)), time.Second), nil
 }
 
-func validateChirp(body string) (string, error) {
-	const maxChirpLength = 140
+func validateChirp(body string, maxChirpLength int) (string, error) {
 	if len(body) > maxChirpLength {
 		return "", errors.New("Chirp is too long")
 	}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/mvusic07/Chirpy/internal/database"
+)
+
+// adminTargetUser looks up the user named in the path for an admin endpoint,
+// responding with an error if there isn't one.
+func (cfg *apiConfig) adminTargetUser(w http.ResponseWriter, r *http.Request) (database.User, bool) {
+	userID, err := uuid.Parse(r.PathValue("userId"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return database.User{}, false
+	}
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return database.User{}, false
+	}
+	return user, true
+}
+
+// canModerate reports whether caller may take moderation action against
+// target: never against themselves, and only against a less privileged role.
+func canModerate(caller principal, target database.User) bool {
+	return caller.UserID != target.ID && outranks(caller.Role, target.Role)
+}
+
+// handlerAdminUnlock clears the failed login counter of an account so its
+// owner can log in again straight away.
+func (cfg *apiConfig) handlerAdminUnlock(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	err := cfg.db.ClearLoginThrottle(r.Context(), accountThrottleKey(user.Email))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't unlock account", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerAdminSuspend blocks an account from logging in and signs it out
+// everywhere. Its chirps stay up.
+func (cfg *apiConfig) handlerAdminSuspend(w http.ResponseWriter, r *http.Request) {
+	caller, _ := requestPrincipal(r)
+	user, ok := cfg.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	if !canModerate(caller, user) {
+		respondWithError(w, http.StatusForbidden, "You can't suspend this account", nil)
+		return
+	}
+
+	user, err := cfg.db.SuspendUser(r.Context(), user.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusConflict, "Account is already suspended", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't suspend account", err)
+		return
+	}
+	if err := cfg.revokeAllSessions(r.Context(), user.ID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't sign out suspended account", err)
+		return
+	}
+	log.Printf("User %s suspended user %s", caller.UserID, user.ID)
+
+	respondWithJSON(w, http.StatusOK, newUser(user))
+}
+
+func (cfg *apiConfig) handlerAdminUnsuspend(w http.ResponseWriter, r *http.Request) {
+	caller, _ := requestPrincipal(r)
+	user, ok := cfg.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	if !canModerate(caller, user) {
+		respondWithError(w, http.StatusForbidden, "You can't unsuspend this account", nil)
+		return
+	}
+
+	user, err := cfg.db.UnsuspendUser(r.Context(), user.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusConflict, "Account is not suspended", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't unsuspend account", err)
+		return
+	}
+	log.Printf("User %s unsuspended user %s", caller.UserID, user.ID)
+
+	respondWithJSON(w, http.StatusOK, newUser(user))
+}
+
+// handlerAdminSetRole changes a user's role. Their access tokens carry the
+// old role, so they are revoked; the next refresh issues one with the new
+// role.
+func (cfg *apiConfig) handlerAdminSetRole(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Role string `json:"role"`
+	}
+
+	caller, _ := requestPrincipal(r)
+	user, ok := cfg.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+	// Admins can't demote themselves, so there is always at least one.
+	if caller.UserID == user.ID {
+		respondWithError(w, http.StatusForbidden, "You can't change your own role", nil)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	if !slices.Contains(knownRoles, params.Role) {
+		respondWithError(w, http.StatusBadRequest, "Unknown role: "+params.Role, nil)
+		return
+	}
+
+	user, err = cfg.db.SetUserRole(r.Context(), database.SetUserRoleParams{
+		ID:   user.ID,
+		Role: params.Role,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't change role", err)
+		return
+	}
+	if err := cfg.revocations.RevokeUser(r.Context(), user.ID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke access tokens", err)
+		return
+	}
+	log.Printf("User %s set the role of user %s to %s", caller.UserID, user.ID, user.Role)
+
+	respondWithJSON(w, http.StatusOK, newUser(user))
+}
@@ -1,56 +1,96 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"net/http"
 
 	"github.com/google/uuid"
-	"github.com/mvusic07/Chirpy/internal/auth"
 )
 
 func (cfg *apiConfig) handlerChirpsRetrieveById(w http.ResponseWriter, r *http.Request) {
-	chirpId := r.PathValue("chirpId")
-	chirp, err := cfg.db.GetChirpById(r.Context(), uuid.MustParse(chirpId))
+	chirpId, err := uuid.Parse(r.PathValue("chirpId"))
 	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+	chirp, err := cfg.db.GetChirpById(r.Context(), chirpId)
+	if err != nil || chirp.DeletedAt.Valid {
 		respondWithError(w, http.StatusNotFound, "Chirp with provided id doesn't exist", err)
+		return
 	}
-	chirpStruct := Chirp{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserID:    chirp.UserID,
+	chirps := []Chirp{newChirp(chirp)}
+	if err := cfg.decorateChirps(r, chirps); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirp", err)
+		return
 	}
-	respondWithJSON(w, http.StatusOK, chirpStruct)
+	respondWithJSON(w, http.StatusOK, chirps[0])
 }
 
+var errChirpNotOwned = errors.New("chirp belongs to another user")
+
 func (cfg *apiConfig) handlerChirpsDeleteById(w http.ResponseWriter, r *http.Request) {
-	token, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
-		return
-	}
-	userId, err := auth.ValidateJWT(token, cfg.tokenSecret)
+	caller, _ := requestPrincipal(r)
+	chirpId, err := uuid.Parse(r.PathValue("chirpId"))
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
 		return
 	}
-	chirpId := r.PathValue("chirpId")
-	chirpfind, err := cfg.db.GetChirpById(r.Context(), uuid.MustParse(chirpId))
-	if err != nil {
+
+	err = cfg.deleteChirp(r.Context(), caller.UserID, chirpId)
+	if errors.Is(err, sql.ErrNoRows) {
 		respondWithError(w, http.StatusNotFound, "Chirp with provided id doesn't exist", err)
 		return
 	}
-	if userId != chirpfind.UserID {
+	if errors.Is(err, errChirpNotOwned) {
 		respondWithError(w, http.StatusForbidden, "User not authorized to delete this chirp", err)
 		return
 	}
-
-	err = cfg.db.DeleteChirpById(r.Context(), uuid.MustParse(chirpId))
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error while deleting a chirp", err)
 		return
 	}
 
 	respondWithJSON(w, http.StatusNoContent, nil)
+}
 
+// deleteChirp removes one of userID's chirps. A chirp with replies or quotes
+// becomes a tombstone so the thread below it survives and the quotes can say
+// it is gone; its rechirps are removed. One without is deleted outright, with
+// its rechirps; the database removes any tombstone it was the last reply to
+// or quote of.
+func (cfg *apiConfig) deleteChirp(ctx context.Context, userID, chirpID uuid.UUID) error {
+	tx, err := cfg.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	chirp, err := qtx.GetChirpForUpdate(ctx, chirpID)
+	if err != nil {
+		return err
+	}
+	if chirp.DeletedAt.Valid {
+		return sql.ErrNoRows
+	}
+	if chirp.UserID != (uuid.NullUUID{UUID: userID, Valid: true}) {
+		return errChirpNotOwned
+	}
+
+	if chirp.ReplyCount > 0 || chirp.QuoteCount > 0 {
+		if err := qtx.TombstoneChirp(ctx, chirp.ID); err != nil {
+			return err
+		}
+		if err := qtx.DeleteRechirpsOf(ctx, uuid.NullUUID{UUID: chirp.ID, Valid: true}); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	if err := qtx.DeleteChirpById(ctx, chirp.ID); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/mvusic07/Chirpy/internal/database"
+)
+
+const (
+	// threadPreviewDepth is how many levels of replies are nested below each
+	// reply on the page. Deeper levels are fetched with the thread of a reply.
+	threadPreviewDepth = 2
+	// threadPreviewReplies is how many replies are nested per chirp; its
+	// reply_count tells whether there are more.
+	threadPreviewReplies = 3
+)
+
+// ThreadReply is a reply with the first of its own replies nested inside.
+type ThreadReply struct {
+	Chirp
+	Replies []ThreadReply `json:"replies"`
+}
+
+// handlerChirpsThread serves a chirp in context: the chain of chirps it
+// replies to, root first, and a page of its replies, oldest first, each with a
+// preview of the conversation below it. Tombstones are included so the thread
+// stays connected.
+func (cfg *apiConfig) handlerChirpsThread(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Ancestors []Chirp       `json:"ancestors"`
+		Chirp     Chirp         `json:"chirp"`
+		Replies   []ThreadReply `json:"replies"`
+	}
+
+	chirpId, err := uuid.Parse(r.PathValue("chirpId"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+	page, err := parseThreadPageParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	chirp, err := cfg.db.GetChirpById(r.Context(), chirpId)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Chirp with provided id doesn't exist", err)
+		return
+	}
+	ancestorRows, err := cfg.db.ListChirpAncestors(r.Context(), chirp.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve thread", err)
+		return
+	}
+	ancestors := []Chirp{}
+	for _, row := range ancestorRows {
+		ancestors = append(ancestors, newChirp(database.Chirp(row)))
+	}
+
+	dbReplies, err := cfg.db.ListReplies(r.Context(), database.ListRepliesParams{
+		ParentID:        uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		CursorCreatedAt: page.Cursor.CreatedAt,
+		CursorID:        page.Cursor.ID,
+		Limit:           page.fetchLimit(),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve replies", err)
+		return
+	}
+	dbReplies = trimPage(w, r, page, dbReplies, chirpCursor)
+
+	nested := []database.Chirp{}
+	level := dbReplies
+	for range threadPreviewDepth {
+		parentIDs := []uuid.UUID{}
+		for _, c := range level {
+			if c.ReplyCount > 0 {
+				parentIDs = append(parentIDs, c.ID)
+			}
+		}
+		if len(parentIDs) == 0 {
+			break
+		}
+		level, err = cfg.db.ListReplyPreviews(r.Context(), database.ListReplyPreviewsParams{
+			ParentIds: parentIDs,
+			PerParent: threadPreviewReplies,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve replies", err)
+			return
+		}
+		nested = append(nested, level...)
+	}
+
+	// Everything is converted up front so it can be decorated at once.
+	all := append(append(ancestors, newChirp(chirp)), newChirps(append(dbReplies, nested...))...)
+	if err := cfg.decorateChirps(r, all); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve thread", err)
+		return
+	}
+	ancestors, all = all[:len(ancestors)], all[len(ancestors):]
+	respondWithJSON(w, http.StatusOK, response{
+		Ancestors: ancestors,
+		Chirp:     all[0],
+		Replies:   buildReplyTree(all[1:len(dbReplies)+1], all[len(dbReplies)+1:]),
+	})
+}
+
+// buildReplyTree nests each of the nested chirps under the chirp it replies
+// to, starting from replies. Order within each level is kept.
+func buildReplyTree(replies, nested []Chirp) []ThreadReply {
+	children := map[uuid.UUID][]Chirp{}
+	for _, c := range nested {
+		if c.InReplyToID != nil {
+			children[*c.InReplyToID] = append(children[*c.InReplyToID], c)
+		}
+	}
+	var build func([]Chirp) []ThreadReply
+	build = func(chirps []Chirp) []ThreadReply {
+		tree := []ThreadReply{}
+		for _, c := range chirps {
+			tree = append(tree, ThreadReply{Chirp: c, Replies: build(children[c.ID])})
+		}
+		return tree
+	}
+	return build(replies)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mvusic07/Chirpy/internal/database"
+)
+
+func TestNewChirp_Reply(t *testing.T) {
+	root := database.Chirp{ID: uuid.New(), Body: "root", ReplyCount: 1}
+	root.ConversationID = root.ID
+	reply := database.Chirp{
+		ID:             uuid.New(),
+		Body:           "reply",
+		InReplyToID:    uuid.NullUUID{UUID: root.ID, Valid: true},
+		ConversationID: root.ID,
+	}
+
+	got := newChirp(root)
+	if got.InReplyToID != nil || got.ReplyCount != 1 || got.Deleted {
+		t.Errorf("Expected a root chirp with one reply, got %+v", got)
+	}
+	got = newChirp(reply)
+	if got.InReplyToID == nil || *got.InReplyToID != root.ID || got.ConversationID != root.ID {
+		t.Errorf("Expected a reply to %s, got %+v", root.ID, got)
+	}
+
+	reply.DeletedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	if !newChirp(reply).Deleted {
+		t.Error("Expected a tombstone to be marked deleted")
+	}
+}
+
+func TestNewChirp_AuthorlessTombstone(t *testing.T) {
+	author := uuid.New()
+	chirp := database.Chirp{ID: uuid.New(), UserID: uuid.NullUUID{UUID: author, Valid: true}}
+	if got := newChirp(chirp).UserID; got == nil || *got != author {
+		t.Errorf("Expected user_id %s, got %v", author, got)
+	}
+
+	// Once the account is deleted, the tombstone has no author at all.
+	chirp.UserID = uuid.NullUUID{}
+	chirp.DeletedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	data, err := json.Marshal(newChirp(chirp))
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"user_id":null`) {
+		t.Errorf("Expected a null user_id, got %s", data)
+	}
+}
+
+func TestBuildReplyTree(t *testing.T) {
+	replyTo := func(parent Chirp) Chirp {
+		return Chirp{ID: uuid.New(), InReplyToID: &parent.ID}
+	}
+	root := Chirp{ID: uuid.New()}
+	a, b := replyTo(root), replyTo(root)
+	a1, a2 := replyTo(a), replyTo(a)
+	a1x := replyTo(a1)
+
+	tree := buildReplyTree([]Chirp{a, b}, []Chirp{a1, a2, a1x})
+
+	if len(tree) != 2 || tree[0].ID != a.ID || tree[1].ID != b.ID {
+		t.Fatalf("Expected replies a and b in order, got %+v", tree)
+	}
+	if len(tree[0].Replies) != 2 || tree[0].Replies[0].ID != a1.ID || tree[0].Replies[1].ID != a2.ID {
+		t.Errorf("Expected a1 and a2 under a, got %+v", tree[0].Replies)
+	}
+	if len(tree[0].Replies[0].Replies) != 1 || tree[0].Replies[0].Replies[0].ID != a1x.ID {
+		t.Errorf("Expected a1x under a1, got %+v", tree[0].Replies[0].Replies)
+	}
+	if tree[1].Replies == nil || len(tree[1].Replies) != 0 {
+		t.Errorf("Expected an empty reply list under b, got %+v", tree[1].Replies)
+	}
+}
@@ -1,26 +1,74 @@
 package main
 
 import (
+	"context"
 	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/mvusic07/Chirpy/internal/database"
 )
 
 func (cfg *apiConfig) handlerChirpsRetrieve(w http.ResponseWriter, r *http.Request) {
-	dbChirps, err := cfg.db.GetChirps(r.Context())
+	page, err := parsePageParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	authorID := uuid.Nil
+	if s := r.URL.Query().Get("author_id"); s != "" {
+		authorID, err = uuid.Parse(s)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid author_id", err)
+			return
+		}
+	}
+
+	dbChirps, err := cfg.listChirps(r.Context(), authorID, page.Desc, page.Cursor, page.fetchLimit())
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirps", err)
 		return
 	}
+	dbChirps = trimPage(w, r, page, dbChirps, chirpCursor)
 
-	chirps := []Chirp{}
-	for _, dbChirp := range dbChirps {
-		chirps = append(chirps, Chirp{
-			ID:        dbChirp.ID,
-			CreatedAt: dbChirp.CreatedAt,
-			UpdatedAt: dbChirp.UpdatedAt,
-			UserID:    dbChirp.UserID,
-			Body:      dbChirp.Body,
-		})
+	chirps := newChirps(dbChirps)
+	if err := cfg.decorateChirps(r, chirps); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirps", err)
+		return
 	}
 
 	respondWithJSON(w, http.StatusOK, chirps)
 }
+
+// listChirps picks the keyset query matching the filter and sort order. Each
+// one is backed by an index on (created_at, id) or (user_id, created_at, id).
+func (cfg *apiConfig) listChirps(ctx context.Context, authorID uuid.UUID, desc bool, cursor pageCursor, limit int32) ([]database.Chirp, error) {
+	switch {
+	case authorID != uuid.Nil && desc:
+		return cfg.db.ListChirpsByAuthorDesc(ctx, database.ListChirpsByAuthorDescParams{
+			UserID:          authorID,
+			CursorCreatedAt: cursor.CreatedAt,
+			CursorID:        cursor.ID,
+			Limit:           limit,
+		})
+	case authorID != uuid.Nil:
+		return cfg.db.ListChirpsByAuthorAsc(ctx, database.ListChirpsByAuthorAscParams{
+			UserID:          authorID,
+			CursorCreatedAt: cursor.CreatedAt,
+			CursorID:        cursor.ID,
+			Limit:           limit,
+		})
+	case desc:
+		return cfg.db.ListChirpsDesc(ctx, database.ListChirpsDescParams{
+			CursorCreatedAt: cursor.CreatedAt,
+			CursorID:        cursor.ID,
+			Limit:           limit,
+		})
+	default:
+		return cfg.db.ListChirpsAsc(ctx, database.ListChirpsAscParams{
+			CursorCreatedAt: cursor.CreatedAt,
+			CursorID:        cursor.ID,
+			Limit:           limit,
+		})
+	}
+}
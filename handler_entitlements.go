@@ -0,0 +1,30 @@
+package main
+
+import "net/http"
+
+func (cfg *apiConfig) handlerEntitlements(w http.ResponseWriter, r *http.Request) {
+	type limits struct {
+		ChirpLength   int `json:"chirp_length"`
+		ChirpsPerHour int `json:"chirps_per_hour"`
+	}
+	type response struct {
+		Tier   string `json:"tier"`
+		Limits limits `json:"limits"`
+	}
+
+	caller, _ := requestPrincipal(r)
+	user, err := cfg.db.GetUserByID(r.Context(), caller.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't find user", err)
+		return
+	}
+
+	e := entitlementsFor(user)
+	respondWithJSON(w, http.StatusOK, response{
+		Tier: e.Tier,
+		Limits: limits{
+			ChirpLength:   e.MaxChirpLength,
+			ChirpsPerHour: e.ChirpsPerHour,
+		},
+	})
+}
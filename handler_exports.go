@@ -0,0 +1,119 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mvusic07/Chirpy/internal/database"
+)
+
+type DataExport struct {
+	ID          uuid.UUID  `json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	DownloadURL string     `json:"download_url,omitempty"`
+}
+
+func (cfg *apiConfig) newDataExport(e database.GetDataExportStatusRow) DataExport {
+	export := DataExport{
+		ID:          e.ID,
+		CreatedAt:   e.CreatedAt,
+		Status:      e.Status,
+		Error:       e.Error.String,
+		CompletedAt: nullTimePtr(e.CompletedAt),
+		ExpiresAt:   nullTimePtr(e.ExpiresAt),
+	}
+	if e.Status == "complete" {
+		export.DownloadURL = cfg.dataExportURL(e.ID) + "/download"
+	}
+	return export
+}
+
+func (cfg *apiConfig) dataExportURL(id uuid.UUID) string {
+	return cfg.baseURL + "/api/exports/" + id.String()
+}
+
+// handlerExportsCreate queues an archive of the caller's data. Asking again
+// while one is in progress returns that one rather than queueing another.
+func (cfg *apiConfig) handlerExportsCreate(w http.ResponseWriter, r *http.Request) {
+	caller, _ := requestPrincipal(r)
+
+	unfinished, err := cfg.db.GetUnfinishedDataExport(r.Context(), caller.UserID)
+	if err == nil {
+		w.Header().Set("Location", cfg.dataExportURL(unfinished.ID))
+		respondWithJSON(w, http.StatusAccepted, cfg.newDataExport(database.GetDataExportStatusRow(unfinished)))
+		return
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't check for exports in progress", err)
+		return
+	}
+
+	export, err := cfg.db.CreateDataExport(r.Context(), caller.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start export", err)
+		return
+	}
+	cfg.exporter.Notify()
+
+	w.Header().Set("Location", cfg.dataExportURL(export.ID))
+	respondWithJSON(w, http.StatusAccepted, cfg.newDataExport(database.GetDataExportStatusRow(export)))
+}
+
+func (cfg *apiConfig) handlerExportsGet(w http.ResponseWriter, r *http.Request) {
+	caller, _ := requestPrincipal(r)
+	exportID, err := uuid.Parse(r.PathValue("exportId"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid export ID", err)
+		return
+	}
+
+	export, err := cfg.db.GetDataExportStatus(r.Context(), database.GetDataExportStatusParams{
+		ID:     exportID,
+		UserID: caller.UserID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Couldn't find export", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't find export", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, cfg.newDataExport(export))
+}
+
+func (cfg *apiConfig) handlerExportsDownload(w http.ResponseWriter, r *http.Request) {
+	caller, _ := requestPrincipal(r)
+	exportID, err := uuid.Parse(r.PathValue("exportId"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid export ID", err)
+		return
+	}
+
+	archive, err := cfg.db.GetDataExportArchive(r.Context(), database.GetDataExportArchiveParams{
+		ID:     exportID,
+		UserID: caller.UserID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Export isn't ready or has expired", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't read export", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="chirpy-export-`+exportID.String()+`.zip"`)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(archive)
+}
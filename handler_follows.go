@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mvusic07/Chirpy/internal/database"
+)
+
+// defaultTimelinePrecomputeThreshold is how many followers an account must
+// have before its chirps are copied into their timelines as they are posted
+// rather than merged in on every read.
+const defaultTimelinePrecomputeThreshold = 500
+
+// Follow is an entry in a list of followers or followed accounts.
+type Follow struct {
+	Profile
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+// followTarget looks up the user named in the path for the follow endpoints,
+// responding with an error if there isn't a visible one.
+func (cfg *apiConfig) followTarget(w http.ResponseWriter, r *http.Request) (database.User, bool) {
+	userID, err := uuid.Parse(r.PathValue("userId"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return database.User{}, false
+	}
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err == nil && (user.SuspendedAt.Valid || user.DeleteAfter.Valid) {
+		err = sql.ErrNoRows
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return database.User{}, false
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't find user", err)
+		return database.User{}, false
+	}
+	return user, true
+}
+
+func (cfg *apiConfig) handlerFollow(w http.ResponseWriter, r *http.Request) {
+	caller, _ := requestPrincipal(r)
+	followee, ok := cfg.followTarget(w, r)
+	if !ok {
+		return
+	}
+	if followee.ID == caller.UserID {
+		respondWithError(w, http.StatusBadRequest, "You can't follow yourself", nil)
+		return
+	}
+
+	n, err := cfg.db.FollowUser(r.Context(), database.FollowUserParams{
+		FollowerID: caller.UserID,
+		FolloweeID: followee.ID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't follow user", err)
+		return
+	}
+	if n > 0 {
+		if err := cfg.updateTimelineAfterFollow(r.Context(), caller.UserID, followee); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't update timeline", err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// updateTimelineAfterFollow copies the followee's recent chirps into the
+// follower's timeline if the followee fans out on write, first switching them
+// to it if they now have enough followers.
+func (cfg *apiConfig) updateTimelineAfterFollow(ctx context.Context, followerID uuid.UUID, followee database.User) error {
+	timeline := precomputedTimeline{db: cfg.db}
+	if !followee.FanOutOnWrite {
+		followers, err := cfg.db.CountFollowers(ctx, followee.ID)
+		if err != nil {
+			return err
+		}
+		if int(followers) < cfg.timelinePrecomputeThreshold {
+			return nil
+		}
+		if err := timeline.Enable(ctx, followee.ID); err != nil {
+			return err
+		}
+	}
+	// Enable has backfilled every follower unless another request switched
+	// the followee over first, so backfill this one either way.
+	return timeline.Followed(ctx, followerID, followee.ID)
+}
+
+func (cfg *apiConfig) handlerUnfollow(w http.ResponseWriter, r *http.Request) {
+	caller, _ := requestPrincipal(r)
+	followeeID, err := uuid.Parse(r.PathValue("userId"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	n, err := cfg.db.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		FollowerID: caller.UserID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't unfollow user", err)
+		return
+	}
+	if n > 0 {
+		err := precomputedTimeline{db: cfg.db}.Unfollowed(r.Context(), caller.UserID, followeeID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't update timeline", err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerFollowersList(w http.ResponseWriter, r *http.Request) {
+	user, page, ok := cfg.parseFollowList(w, r)
+	if !ok {
+		return
+	}
+	rows, err := cfg.db.ListFollowers(r.Context(), database.ListFollowersParams{
+		UserID:          user.ID,
+		CursorCreatedAt: page.Cursor.CreatedAt,
+		CursorID:        page.Cursor.ID,
+		Limit:           page.fetchLimit(),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve followers", err)
+		return
+	}
+	respondWithFollows(w, r, page, rows)
+}
+
+func (cfg *apiConfig) handlerFollowingList(w http.ResponseWriter, r *http.Request) {
+	user, page, ok := cfg.parseFollowList(w, r)
+	if !ok {
+		return
+	}
+	rows, err := cfg.db.ListFollowing(r.Context(), database.ListFollowingParams{
+		UserID:          user.ID,
+		CursorCreatedAt: page.Cursor.CreatedAt,
+		CursorID:        page.Cursor.ID,
+		Limit:           page.fetchLimit(),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve followed accounts", err)
+		return
+	}
+	// Both lists have the same shape.
+	followers := make([]database.ListFollowersRow, len(rows))
+	for i, row := range rows {
+		followers[i] = database.ListFollowersRow(row)
+	}
+	respondWithFollows(w, r, page, followers)
+}
+
+func (cfg *apiConfig) parseFollowList(w http.ResponseWriter, r *http.Request) (database.User, pageParams, bool) {
+	user, ok := cfg.followTarget(w, r)
+	if !ok {
+		return database.User{}, pageParams{}, false
+	}
+	page, err := parseFeedPageParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return database.User{}, pageParams{}, false
+	}
+	return user, page, true
+}
+
+// respondWithFollows writes a page of a follow list, fetched with
+// page.fetchLimit.
+func respondWithFollows(w http.ResponseWriter, r *http.Request, page pageParams, rows []database.ListFollowersRow) {
+	rows = trimPage(w, r, page, rows, func(row database.ListFollowersRow) pageCursor {
+		return pageCursor{CreatedAt: row.FollowedAt, ID: row.User.ID}
+	})
+
+	follows := []Follow{}
+	for _, row := range rows {
+		follows = append(follows, Follow{
+			Profile:    newProfile(row.User),
+			FollowedAt: row.FollowedAt,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, follows)
+}
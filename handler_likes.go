@@ -0,0 +1,198 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mvusic07/Chirpy/internal/database"
+)
+
+// Like is an entry in the list of who liked a chirp.
+type Like struct {
+	Profile
+	LikedAt time.Time `json:"liked_at"`
+}
+
+// LikedChirp is an entry in the list of chirps a user liked.
+type LikedChirp struct {
+	Chirp
+	LikedAt time.Time `json:"liked_at"`
+}
+
+// likeTarget looks up the chirp named in the path, responding with an error
+// if there isn't one. Tombstones can't be liked or listed, and a rechirp
+// stands for the chirp it shares.
+func (cfg *apiConfig) likeTarget(w http.ResponseWriter, r *http.Request) (database.Chirp, bool) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpId"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return database.Chirp{}, false
+	}
+	chirp, err := cfg.db.GetChirpById(r.Context(), chirpID)
+	if err == nil && chirp.RechirpOfID.Valid {
+		chirp, err = cfg.db.GetChirpById(r.Context(), chirp.RechirpOfID.UUID)
+	}
+	if err != nil || chirp.DeletedAt.Valid {
+		respondWithError(w, http.StatusNotFound, "Chirp with provided id doesn't exist", err)
+		return database.Chirp{}, false
+	}
+	return chirp, true
+}
+
+// handlerLike likes a chirp. Liking it again changes nothing.
+func (cfg *apiConfig) handlerLike(w http.ResponseWriter, r *http.Request) {
+	caller, _ := requestPrincipal(r)
+	chirp, ok := cfg.likeTarget(w, r)
+	if !ok {
+		return
+	}
+	_, err := cfg.db.LikeChirp(r.Context(), database.LikeChirpParams{
+		UserID:  caller.UserID,
+		ChirpID: chirp.ID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't like chirp", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerUnlike takes back a like. Unliking a chirp that isn't liked, or no
+// longer exists, changes nothing. A tombstone's like can still be taken back.
+func (cfg *apiConfig) handlerUnlike(w http.ResponseWriter, r *http.Request) {
+	caller, _ := requestPrincipal(r)
+	chirpID, err := uuid.Parse(r.PathValue("chirpId"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+	chirpID, err = cfg.undoTarget(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't find chirp", err)
+		return
+	}
+	_, err = cfg.db.UnlikeChirp(r.Context(), database.UnlikeChirpParams{
+		UserID:  caller.UserID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't unlike chirp", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handlerChirpLikesList lists who liked a chirp, most recent first.
+func (cfg *apiConfig) handlerChirpLikesList(w http.ResponseWriter, r *http.Request) {
+	chirp, ok := cfg.likeTarget(w, r)
+	if !ok {
+		return
+	}
+	page, err := parseFeedPageParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	rows, err := cfg.db.ListChirpLikers(r.Context(), database.ListChirpLikersParams{
+		ChirpID:         chirp.ID,
+		CursorCreatedAt: page.Cursor.CreatedAt,
+		CursorID:        page.Cursor.ID,
+		Limit:           page.fetchLimit(),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve likes", err)
+		return
+	}
+	rows = trimPage(w, r, page, rows, func(row database.ListChirpLikersRow) pageCursor {
+		return pageCursor{CreatedAt: row.LikedAt, ID: row.User.ID}
+	})
+
+	likes := []Like{}
+	for _, row := range rows {
+		likes = append(likes, Like{
+			Profile: newProfile(row.User),
+			LikedAt: row.LikedAt,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, likes)
+}
+
+// handlerUserLikesList lists the chirps a user liked, most recently liked
+// first.
+func (cfg *apiConfig) handlerUserLikesList(w http.ResponseWriter, r *http.Request) {
+	user, ok := cfg.followTarget(w, r)
+	if !ok {
+		return
+	}
+	page, err := parseFeedPageParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	rows, err := cfg.db.ListLikedChirps(r.Context(), database.ListLikedChirpsParams{
+		UserID:          user.ID,
+		CursorCreatedAt: page.Cursor.CreatedAt,
+		CursorID:        page.Cursor.ID,
+		Limit:           page.fetchLimit(),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve liked chirps", err)
+		return
+	}
+	rows = trimPage(w, r, page, rows, func(row database.ListLikedChirpsRow) pageCursor {
+		return pageCursor{CreatedAt: row.LikedAt, ID: row.Chirp.ID}
+	})
+
+	chirps := []Chirp{}
+	for _, row := range rows {
+		chirps = append(chirps, newChirp(row.Chirp))
+	}
+	if err := cfg.decorateChirps(r, chirps); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve liked chirps", err)
+		return
+	}
+
+	liked := []LikedChirp{}
+	for i, row := range rows {
+		liked = append(liked, LikedChirp{
+			Chirp:   chirps[i],
+			LikedAt: row.LikedAt,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, liked)
+}
+
+// attachLikedByMe sets LikedByMe on the chirps the caller has liked. It does
+// nothing for anonymous callers, or tokens that can't read chirps as the user.
+func (cfg *apiConfig) attachLikedByMe(r *http.Request, chirps []Chirp) error {
+	caller, ok := requestPrincipal(r)
+	if !ok || !caller.HasScope(scopeChirpsRead) || len(chirps) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(chirps))
+	for i, chirp := range chirps {
+		ids[i] = chirp.ID
+	}
+	liked, err := cfg.db.ListLikedChirpIDs(r.Context(), database.ListLikedChirpIDsParams{
+		UserID:   caller.UserID,
+		ChirpIds: ids,
+	})
+	if err != nil {
+		return err
+	}
+	setLikedByMe(chirps, liked)
+	return nil
+}
+
+func setLikedByMe(chirps []Chirp, liked []uuid.UUID) {
+	likedSet := make(map[uuid.UUID]bool, len(liked))
+	for _, id := range liked {
+		likedSet[id] = true
+	}
+	for i := range chirps {
+		chirps[i].LikedByMe = likedSet[chirps[i].ID]
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSetLikedByMe(t *testing.T) {
+	chirps := []Chirp{{ID: uuid.New()}, {ID: uuid.New(), LikedByMe: true}, {ID: uuid.New()}}
+	setLikedByMe(chirps, []uuid.UUID{chirps[2].ID})
+
+	for i, want := range []bool{false, false, true} {
+		if chirps[i].LikedByMe != want {
+			t.Errorf("Chirp %d: expected liked_by_me %v, got %v", i, want, chirps[i].LikedByMe)
+		}
+	}
+}
+
+func TestAttachLikedByMe_SkipsCallersWithoutChirpsRead(t *testing.T) {
+	// No database is configured, so a lookup would panic.
+	cfg := &apiConfig{}
+	chirps := []Chirp{{ID: uuid.New()}}
+
+	r := httptest.NewRequest("GET", "/api/chirps", nil)
+	if err := cfg.attachLikedByMe(r, chirps); err != nil {
+		t.Errorf("Expected no lookup for an anonymous caller, got %v", err)
+	}
+
+	caller := principal{UserID: uuid.New(), Scopes: []string{scopeChirpsWrite}}
+	r = r.WithContext(context.WithValue(r.Context(), principalContextKey{}, caller))
+	if err := cfg.attachLikedByMe(r, chirps); err != nil {
+		t.Errorf("Expected no lookup for a token without chirps:read, got %v", err)
+	}
+	if chirps[0].LikedByMe {
+		t.Error("Expected liked_by_me to stay false")
+	}
+}
+
+func TestLike_RoundTripThroughRechirp(t *testing.T) {
+	s := newTestServer(t)
+	author := s.signup(t, "lydia@example.com")
+	sharer := s.signup(t, "tyrus@example.com")
+	fan := s.signup(t, "victor@example.com")
+	original := s.postChirp(t, author, map[string]any{"body": "like me"})
+
+	var rechirp Chirp
+	decode(t, s.do(t, "POST", "/api/chirps/"+original.ID.String()+"/rechirp", sharer.Token, nil), http.StatusCreated, &rechirp)
+	path := "/api/chirps/" + rechirp.ID.String() + "/like"
+
+	likeCount := func() int {
+		t.Helper()
+		var got Chirp
+		decode(t, s.do(t, "GET", "/api/chirps/"+original.ID.String(), "", nil), http.StatusOK, &got)
+		return got.LikeCount
+	}
+
+	decode(t, s.do(t, "POST", path, fan.Token, nil), http.StatusNoContent, nil)
+	if n := likeCount(); n != 1 {
+		t.Fatalf("Expected liking the rechirp to like the original, got like_count %d", n)
+	}
+	decode(t, s.do(t, "DELETE", path, fan.Token, nil), http.StatusNoContent, nil)
+	if n := likeCount(); n != 0 {
+		t.Errorf("Expected unliking the rechirp to unlike the original, got like_count %d", n)
+	}
+}
+
+func TestUnlike_Tombstone(t *testing.T) {
+	s := newTestServer(t)
+	author := s.signup(t, "hector@example.com")
+	fan := s.signup(t, "gale@example.com")
+	chirp := s.postChirp(t, author, map[string]any{"body": "going soon"})
+	s.postChirp(t, fan, map[string]any{"body": "keeps it around", "in_reply_to_id": chirp.ID})
+	path := "/api/chirps/" + chirp.ID.String()
+
+	decode(t, s.do(t, "POST", path+"/like", fan.Token, nil), http.StatusNoContent, nil)
+	decode(t, s.do(t, "DELETE", path, author.Token, nil), http.StatusNoContent, nil)
+	decode(t, s.do(t, "DELETE", path+"/like", fan.Token, nil), http.StatusNoContent, nil)
+
+	var likes int
+	if err := s.conn.QueryRow("SELECT COUNT(*) FROM likes WHERE chirp_id = $1", chirp.ID).Scan(&likes); err != nil {
+		t.Fatalf("Couldn't count likes: %v", err)
+	}
+	if likes != 0 {
+		t.Errorf("Expected the tombstone's like to be removed, %d left", likes)
+	}
+}
@@ -1,67 +1,189 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
-	"github.com/mvusic07/Chirpy/internal/auth"
 	"github.com/mvusic07/Chirpy/internal/database"
 )
 
 func (cfg *apiConfig) handlerLogin(w http.ResponseWriter, r *http.Request) {
 	type parametri struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Email       string `json:"email"`
+		Password    string `json:"password"`
+		SessionName string `json:"session_name"`
 	}
-	decoder := json.NewDecoder(r.Body)
-	type respone struct {
-		User
-		Token        string `json:"token"`
-		RefreshToken string `json:"refresh_token"`
+	type mfaResponse struct {
+		MFARequired bool   `json:"mfa_required"`
+		MFAToken    string `json:"mfa_token"`
 	}
+	decoder := json.NewDecoder(r.Body)
 	params := parametri{}
 	err := decoder.Decode(&params)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
 		return
 	}
-	dbdata, err := cfg.db.GetUserByEmail(r.Context(), params.Email)
-	if err != nil {
+
+	dbdata, err := cfg.checkCredentials(r, params.Email, params.Password)
+	var throttled *loginThrottledError
+	if errors.As(err, &throttled) {
+		respondWithRetryAfter(w, throttled.retryAfter)
+		return
+	}
+	if errors.Is(err, errInvalidCredentials) {
 		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password", err)
 		return
 	}
-	err = auth.CheckPasswordHash(params.Password, dbdata.HashedPassword)
+	if errors.Is(err, errAccountSuspended) {
+		respondWithError(w, http.StatusForbidden, "This account has been suspended", err)
+		return
+	}
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password", err)
+		respondWithError(w, http.StatusInternalServerError, "Couldn't check login attempts", err)
 		return
 	}
 
-	accessToken, err := auth.MakeJWT(dbdata.ID, cfg.tokenSecret, time.Hour)
+	totp, err := cfg.db.GetTOTP(r.Context(), dbdata.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't check two-factor authentication", err)
+		return
+	}
+	if err == nil && totp.ConfirmedAt.Valid {
+		mfaToken, err := cfg.keys.MakeMFAChallenge(dbdata.ID, params.SessionName, mfaChallengeTTL)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't create MFA challenge", err)
+			return
+		}
+		respondWithJSON(w, http.StatusOK, mfaResponse{
+			MFARequired: true,
+			MFAToken:    mfaToken,
+		})
+		return
+	}
+
+	cfg.clearAccountThrottle(r.Context(), dbdata.Email)
+	cfg.startSession(w, r, dbdata, params.SessionName)
+}
+
+var (
+	errInvalidCredentials = errors.New("incorrect email or password")
+	errAccountSuspended   = errors.New("account suspended")
+)
+
+// loginThrottledError means too many logins have failed recently for the
+// account or the client's IP address.
+type loginThrottledError struct {
+	retryAfter time.Duration
+}
+
+func (e *loginThrottledError) Error() string {
+	return "too many failed login attempts"
+}
+
+// checkCredentials checks an email and password, counting failures towards
+// the login throttle. It returns a *loginThrottledError while the account or
+// IP is locked out, errInvalidCredentials if either is wrong and
+// errAccountSuspended if both are right but a moderator has suspended the
+// account. The account's failures aren't cleared here: a password is only
+// half a login for an account with a second factor, which counts its wrong
+// codes towards the same lockout. Callers clear them with
+// clearAccountThrottle once the whole login has succeeded.
+func (cfg *apiConfig) checkCredentials(r *http.Request, email, password string) (database.User, error) {
+	accountKey := accountThrottleKey(email)
+	ipKey := ipThrottleKey(clientIP(r))
+	retryAfter, err := cfg.loginRetryAfter(r.Context(), accountKey, ipKey)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Couldn't create access JWT", err)
+		return database.User{}, err
+	}
+	if retryAfter > 0 {
+		return database.User{}, &loginThrottledError{retryAfter: retryAfter}
+	}
+
+	// An invalid email can't belong to an account, and is handled just like
+	// an unknown one.
+	user, err := cfg.getUserByEmail(r.Context(), email)
+	if err != nil {
+		// Verifying against no hash still costs a full hash, so response
+		// times don't reveal which emails are registered.
+		cfg.passwords.Verify(password, "")
+		cfg.recordLoginFailure(r.Context(), accountKey, accountThrottle)
+		cfg.recordLoginFailure(r.Context(), ipKey, ipThrottle)
+		return database.User{}, errInvalidCredentials
+	}
+	needsRehash, err := cfg.passwords.Verify(password, user.HashedPassword)
+	if err != nil {
+		cfg.recordLoginFailure(r.Context(), accountKey, accountThrottle)
+		cfg.recordLoginFailure(r.Context(), ipKey, ipThrottle)
+		return database.User{}, errInvalidCredentials
+	}
+	if needsRehash {
+		cfg.rehashPassword(r.Context(), user.ID, password)
+	}
+	if user.SuspendedAt.Valid {
+		return database.User{}, errAccountSuspended
+	}
+	return user, nil
+}
+
+// startSession completes a login: it creates a session for user and responds
+// with the user and a fresh access and refresh token pair.
+func (cfg *apiConfig) startSession(w http.ResponseWriter, r *http.Request, user database.User, sessionName string) {
+	type respone struct {
+		User
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	tx, err := cfg.conn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
 		return
 	}
-	refreshToken := auth.MakeRefreshToken()
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	// Logging in during the deletion grace period reopens the account.
+	if user.DeleteAfter.Valid {
+		if err := qtx.CancelUserDeletion(r.Context(), user.ID); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't reopen account", err)
+			return
+		}
+		user.DeleteAfter = sql.NullTime{}
+	}
 
-	_, err = cfg.db.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
-		UserID:    dbdata.ID,
-		Token:     refreshToken,
-		ExpiresAt: time.Now().UTC().Add(time.Hour * 24 * 60),
+	session, err := qtx.CreateSession(r.Context(), database.CreateSessionParams{
+		UserID:    user.ID,
+		Name:      sessionName,
+		UserAgent: r.UserAgent(),
+		Ip:        clientIP(r),
 	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create session", err)
+		return
+	}
 
+	accessToken, err := cfg.keys.MakeRoleJWT(user.ID, session.ID, user.Role, accessTokenTTL)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create access JWT", err)
+		return
+	}
+	refreshToken, err := createRefreshToken(r.Context(), qtx, user.ID, session.ID, "")
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Couldn't save refresh token", err)
 		return
 	}
 
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save refresh token", err)
+		return
+	}
+
 	respondWithJSON(w, http.StatusOK, respone{
-		User: User{
-			ID:        dbdata.ID,
-			Email:     dbdata.Email,
-			CreatedAt: dbdata.CreatedAt,
-			UpdatedAt: dbdata.UpdatedAt,
-		},
+		User:         newUser(user),
 		Token:        accessToken,
 		RefreshToken: refreshToken,
 	})
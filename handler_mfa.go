@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/mvusic07/Chirpy/internal/auth"
+	"github.com/mvusic07/Chirpy/internal/database"
+)
+
+const (
+	mfaChallengeTTL   = 5 * time.Minute
+	recoveryCodeCount = 10
+)
+
+var errInvalidSecondFactor = errors.New("invalid two-factor code")
+
+// checkSecondFactor accepts either a current TOTP code or an unused recovery
+// code. Both are single-use: a TOTP code can't be replayed within its window
+// and a recovery code is spent.
+func (cfg *apiConfig) checkSecondFactor(ctx context.Context, totp database.UserTotp, code, recoveryCode string) error {
+	if recoveryCode != "" {
+		n, err := cfg.db.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+			CodeHash: auth.HashRecoveryCode(recoveryCode),
+			UserID:   totp.UserID,
+		})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return errInvalidSecondFactor
+		}
+		return nil
+	}
+
+	step, err := auth.ValidateTOTP(totp.Secret, code, time.Now())
+	if err != nil {
+		return errInvalidSecondFactor
+	}
+	n, err := cfg.db.UseTOTPStep(ctx, database.UseTOTPStepParams{
+		UserID:       totp.UserID,
+		LastUsedStep: step,
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errInvalidSecondFactor
+	}
+	return nil
+}
+
+func (cfg *apiConfig) handlerLoginMFA(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		MFAToken     string `json:"mfa_token"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	challenge, err := cfg.keys.ParseMFAChallenge(params.MFAToken)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired MFA token", err)
+		return
+	}
+	userID, err := challenge.UserID()
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired MFA token", err)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired MFA token", err)
+		return
+	}
+	if user.SuspendedAt.Valid {
+		respondWithError(w, http.StatusForbidden, "This account has been suspended", errAccountSuspended)
+		return
+	}
+	totp, err := cfg.db.GetTOTP(r.Context(), userID)
+	if err != nil || !totp.ConfirmedAt.Valid {
+		respondWithError(w, http.StatusUnauthorized, "Two-factor authentication is not enabled", err)
+		return
+	}
+
+	// Codes are only six digits, so guesses count towards the same lockout
+	// as wrong passwords.
+	accountKey := accountThrottleKey(user.Email)
+	ipKey := ipThrottleKey(clientIP(r))
+	retryAfter, err := cfg.loginRetryAfter(r.Context(), accountKey, ipKey)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't check login attempts", err)
+		return
+	}
+	if retryAfter > 0 {
+		respondWithRetryAfter(w, retryAfter)
+		return
+	}
+
+	err = cfg.checkSecondFactor(r.Context(), totp, params.Code, params.RecoveryCode)
+	if errors.Is(err, errInvalidSecondFactor) {
+		cfg.recordLoginFailure(r.Context(), accountKey, accountThrottle)
+		cfg.recordLoginFailure(r.Context(), ipKey, ipThrottle)
+		respondWithError(w, http.StatusUnauthorized, "Invalid two-factor code", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't check two-factor code", err)
+		return
+	}
+	cfg.clearAccountThrottle(r.Context(), user.Email)
+
+	cfg.startSession(w, r, user, challenge.SessionName)
+}
+
+func (cfg *apiConfig) handlerTOTPEnroll(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Secret     string `json:"secret"`
+		OTPAuthURI string `json:"otpauth_uri"`
+	}
+
+	caller, _ := requestPrincipal(r)
+	userID := caller.UserID
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+
+	totp, err := cfg.db.UpsertPendingTOTP(r.Context(), database.UpsertPendingTOTPParams{
+		UserID: userID,
+		Secret: auth.GenerateTOTPSecret(),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start enrollment", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response{
+		Secret:     totp.Secret,
+		OTPAuthURI: auth.TOTPURI(totp.Secret, "Chirpy", user.Email),
+	})
+}
+
+func (cfg *apiConfig) handlerTOTPConfirm(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Code string `json:"code"`
+	}
+	type response struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+
+	caller, _ := requestPrincipal(r)
+	userID := caller.UserID
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	totp, err := cfg.db.GetTOTP(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "No enrollment in progress", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't find enrollment", err)
+		return
+	}
+	if totp.ConfirmedAt.Valid {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled", nil)
+		return
+	}
+	step, err := auth.ValidateTOTP(totp.Secret, params.Code, time.Now())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid two-factor code", err)
+		return
+	}
+
+	tx, err := cfg.conn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	err = qtx.ConfirmTOTP(r.Context(), database.ConfirmTOTPParams{
+		UserID:       userID,
+		LastUsedStep: step,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't enable two-factor authentication", err)
+		return
+	}
+	err = qtx.DeleteRecoveryCodes(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create recovery codes", err)
+		return
+	}
+	codes := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	for _, code := range codes {
+		err = qtx.CreateRecoveryCode(r.Context(), database.CreateRecoveryCodeParams{
+			CodeHash: auth.HashRecoveryCode(code),
+			UserID:   userID,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't create recovery codes", err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't enable two-factor authentication", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, response{
+		RecoveryCodes: codes,
+	})
+}
+
+func (cfg *apiConfig) handlerTOTPDisable(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	caller, _ := requestPrincipal(r)
+	userID := caller.UserID
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	totp, err := cfg.db.GetTOTP(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Two-factor authentication is not enabled", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't find two-factor settings", err)
+		return
+	}
+	// A pending enrollment can be abandoned freely; an active one needs a
+	// second factor so a stolen access token alone can't turn it off. As at
+	// login, guesses count towards the account and IP lockouts.
+	if totp.ConfirmedAt.Valid {
+		user, err := cfg.db.GetUserByID(r.Context(), userID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't find user", err)
+			return
+		}
+		accountKey := accountThrottleKey(user.Email)
+		ipKey := ipThrottleKey(clientIP(r))
+		retryAfter, err := cfg.loginRetryAfter(r.Context(), accountKey, ipKey)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't check login attempts", err)
+			return
+		}
+		if retryAfter > 0 {
+			respondWithRetryAfter(w, retryAfter)
+			return
+		}
+
+		err = cfg.checkSecondFactor(r.Context(), totp, params.Code, params.RecoveryCode)
+		if errors.Is(err, errInvalidSecondFactor) {
+			cfg.recordLoginFailure(r.Context(), accountKey, accountThrottle)
+			cfg.recordLoginFailure(r.Context(), ipKey, ipThrottle)
+			respondWithError(w, http.StatusUnauthorized, "Invalid two-factor code", err)
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't check two-factor code", err)
+			return
+		}
+	}
+
+	tx, err := cfg.conn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	if err := qtx.DeleteTOTP(r.Context(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't disable two-factor authentication", err)
+		return
+	}
+	if err := qtx.DeleteRecoveryCodes(r.Context(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't disable two-factor authentication", err)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't disable two-factor authentication", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mvusic07/Chirpy/internal/auth"
+)
+
+// enableTOTP enrolls session's account in TOTP and returns the secret.
+func (s *testServer) enableTOTP(t *testing.T, session testSession) string {
+	t.Helper()
+	var enrollment struct {
+		Secret string `json:"secret"`
+	}
+	decode(t, s.do(t, "POST", "/api/mfa/totp/enroll", session.Token, nil), http.StatusOK, &enrollment)
+	code, err := auth.TOTPCode(enrollment.Secret, time.Now())
+	if err != nil {
+		t.Fatalf("TOTPCode failed: %v", err)
+	}
+	decode(t, s.do(t, "POST", "/api/mfa/totp/confirm", session.Token, map[string]string{
+		"code": code,
+	}), http.StatusOK, nil)
+	return enrollment.Secret
+}
+
+// wrongTOTPCode returns a code that isn't the current one for secret.
+func wrongTOTPCode(t *testing.T, secret string) string {
+	t.Helper()
+	code, err := auth.TOTPCode(secret, time.Now())
+	if err != nil {
+		t.Fatalf("TOTPCode failed: %v", err)
+	}
+	n, _ := strconv.Atoi(code)
+	return fmt.Sprintf("%06d", (n+1)%1000000)
+}
+
+func TestLoginMFA_PasswordDoesNotResetLockout(t *testing.T) {
+	s := newTestServer(t)
+	session := s.signup(t, "nacho@example.com")
+	secret := s.enableTOTP(t, session)
+
+	challenge := func() string {
+		var res struct {
+			MFAToken string `json:"mfa_token"`
+		}
+		decode(t, s.do(t, "POST", "/api/login", "", map[string]string{
+			"email":    "nacho@example.com",
+			"password": testPassword,
+		}), http.StatusOK, &res)
+		return res.MFAToken
+	}
+
+	// Logging in with the password again between guesses must not give the
+	// guesser a fresh set of attempts.
+	for range accountThrottle.freeAttempts + 1 {
+		rec := s.do(t, "POST", "/api/login/mfa", "", map[string]string{
+			"mfa_token": challenge(),
+			"code":      wrongTOTPCode(t, secret),
+		})
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected a wrong code to be rejected, got %d: %s", rec.Code, rec.Body)
+		}
+	}
+
+	rec := s.do(t, "POST", "/api/login", "", map[string]string{
+		"email":    "nacho@example.com",
+		"password": testPassword,
+	})
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected the account to be locked out, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestTOTPDisable_Throttled(t *testing.T) {
+	s := newTestServer(t)
+	session := s.signup(t, "mike@example.com")
+	secret := s.enableTOTP(t, session)
+
+	for range accountThrottle.freeAttempts + 1 {
+		rec := s.do(t, "DELETE", "/api/mfa/totp", session.Token, map[string]string{
+			"code": wrongTOTPCode(t, secret),
+		})
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected a wrong code to be rejected, got %d: %s", rec.Code, rec.Body)
+		}
+	}
+
+	// Once locked out, even the right code has to wait.
+	code, err := auth.TOTPCode(secret, time.Now())
+	if err != nil {
+		t.Fatalf("TOTPCode failed: %v", err)
+	}
+	rec := s.do(t, "DELETE", "/api/mfa/totp", session.Token, map[string]string{"code": code})
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected disabling to be throttled, got %d: %s", rec.Code, rec.Body)
+	}
+}
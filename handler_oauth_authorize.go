@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mvusic07/Chirpy/internal/auth"
+	"github.com/mvusic07/Chirpy/internal/database"
+)
+
+const authorizationCodeTTL = 10 * time.Minute
+
+// scopeDescriptions are shown on the consent page.
+var scopeDescriptions = map[string]string{
+	scopeChirpsRead:   "Read chirps on your behalf",
+	scopeChirpsWrite:  "Post and delete chirps as you",
+	scopeProfileWrite: "Change your profile",
+	scopeFollowsWrite: "Follow and unfollow accounts as you",
+	scopeLikesWrite:   "Like and unlike chirps as you",
+}
+
+// authorizeRequest is a validated OAuth authorization request (RFC 6749
+// section 4.1.1 with PKCE).
+type authorizeRequest struct {
+	client        database.OauthClient
+	redirectURI   string
+	state         string
+	scopes        []string
+	codeChallenge string
+}
+
+// oauthError is an error response as defined by RFC 6749.
+type oauthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *oauthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+// parseAuthorizeRequest validates an authorization request. A problem with
+// the client or redirect URI is returned as a plain error and must be shown
+// to the user, since redirecting to an unverified URI would make Chirpy an
+// open redirector. Anything else is returned as an *oauthError to be sent
+// back to the client in the redirect.
+func (cfg *apiConfig) parseAuthorizeRequest(ctx context.Context, v url.Values) (authorizeRequest, *oauthError, error) {
+	clientID, err := uuid.Parse(v.Get("client_id"))
+	if err != nil {
+		return authorizeRequest{}, nil, errors.New("the client_id is missing or malformed")
+	}
+	client, err := cfg.db.GetOAuthClient(ctx, clientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return authorizeRequest{}, nil, errors.New("the application is not registered")
+	}
+	if err != nil {
+		return authorizeRequest{}, nil, err
+	}
+
+	req := authorizeRequest{
+		client:      client,
+		redirectURI: v.Get("redirect_uri"),
+		state:       v.Get("state"),
+	}
+	if req.redirectURI == "" && len(client.RedirectUris) == 1 {
+		req.redirectURI = client.RedirectUris[0]
+	}
+	if !slices.Contains(client.RedirectUris, req.redirectURI) {
+		return authorizeRequest{}, nil, errors.New("the redirect_uri is not registered for this application")
+	}
+
+	if v.Get("response_type") != "code" {
+		return req, &oauthError{Code: "unsupported_response_type", Description: "Only the code response type is supported"}, nil
+	}
+	req.scopes = strings.Fields(v.Get("scope"))
+	if len(req.scopes) == 0 {
+		return req, &oauthError{Code: "invalid_scope", Description: "A scope is required"}, nil
+	}
+	for _, scope := range req.scopes {
+		if !slices.Contains(client.Scopes, scope) {
+			return req, &oauthError{Code: "invalid_scope", Description: "Scope " + scope + " is not allowed for this client"}, nil
+		}
+	}
+	slices.Sort(req.scopes)
+	req.scopes = slices.Compact(req.scopes)
+
+	// PKCE is required of every client, with S256 only (RFC 9700).
+	req.codeChallenge = v.Get("code_challenge")
+	if len(req.codeChallenge) != 43 || v.Get("code_challenge_method") != "S256" {
+		return req, &oauthError{Code: "invalid_request", Description: "A code_challenge with code_challenge_method S256 is required"}, nil
+	}
+	return req, nil, nil
+}
+
+// redirectToClient sends the user agent back to the client with params added
+// to its redirect URI.
+func redirectToClient(w http.ResponseWriter, r *http.Request, req authorizeRequest, params url.Values) {
+	u, err := url.Parse(req.redirectURI)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Invalid redirect URI", err)
+		return
+	}
+	if req.state != "" {
+		params.Set("state", req.state)
+	}
+	query := u.Query()
+	for k, vs := range params {
+		query[k] = vs
+	}
+	u.RawQuery = query.Encode()
+	// 303 so the browser follows with a GET and can't repost the password
+	// from the consent form to the client (RFC 9700 section 4.11).
+	http.Redirect(w, r, u.String(), http.StatusSeeOther)
+}
+
+func redirectWithOAuthError(w http.ResponseWriter, r *http.Request, req authorizeRequest, oerr *oauthError) {
+	params := url.Values{"error": {oerr.Code}}
+	if oerr.Description != "" {
+		params.Set("error_description", oerr.Description)
+	}
+	redirectToClient(w, r, req, params)
+}
+
+type consentScope struct {
+	Name        string
+	Description string
+}
+
+type consentPage struct {
+	ClientName string
+	Scopes     []consentScope
+	Params     url.Values
+	Email      string
+	Error      string
+}
+
+var consentTemplate = template.Must(template.New("consent").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Authorize {{.ClientName}} - Chirpy</title>
+</head>
+<body>
+<h1>Authorize {{.ClientName}}</h1>
+{{if .Error}}<p role="alert"><strong>{{.Error}}</strong></p>{{end}}
+{{if .Scopes}}
+<p>{{.ClientName}} would like to:</p>
+<ul>
+{{range .Scopes}}<li>{{.Description}} <code>{{.Name}}</code></li>
+{{end}}</ul>
+<form method="post">
+{{range $name, $values := .Params}}{{range $values}}<input type="hidden" name="{{$name}}" value="{{.}}">
+{{end}}{{end}}<p><label>Email <input type="email" name="email" value="{{.Email}}" autocomplete="username" required></label></p>
+<p><label>Password <input type="password" name="password" autocomplete="current-password" required></label></p>
+<p><label>Two-factor or recovery code, if enabled <input type="text" name="code" autocomplete="one-time-code"></label></p>
+<p><button type="submit" name="decision" value="allow">Allow</button>
+<button type="submit" name="decision" value="deny" formnovalidate>Deny</button></p>
+</form>
+{{end}}
+</body>
+</html>
+`))
+
+// authorizeParams are the request parameters carried through the consent
+// form.
+var authorizeParams = []string{"response_type", "client_id", "redirect_uri", "scope", "state", "code_challenge", "code_challenge_method"}
+
+func renderConsentPage(w http.ResponseWriter, code int, page consentPage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	// Never let another site frame the page and trick users into approving.
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; frame-ancestors 'none'")
+	w.WriteHeader(code)
+	if err := consentTemplate.Execute(w, page); err != nil {
+		log.Printf("Couldn't render consent page: %s", err)
+	}
+}
+
+func newConsentPage(req authorizeRequest, v url.Values) consentPage {
+	page := consentPage{
+		ClientName: req.client.Name,
+		Params:     url.Values{},
+	}
+	for _, scope := range req.scopes {
+		page.Scopes = append(page.Scopes, consentScope{Name: scope, Description: scopeDescriptions[scope]})
+	}
+	for _, name := range authorizeParams {
+		if v.Has(name) {
+			page.Params[name] = v[name]
+		}
+	}
+	return page
+}
+
+func (cfg *apiConfig) handlerOAuthAuthorize(w http.ResponseWriter, r *http.Request) {
+	req, oerr, err := cfg.parseAuthorizeRequest(r.Context(), r.URL.Query())
+	if err != nil {
+		renderConsentPage(w, http.StatusBadRequest, consentPage{ClientName: "application", Error: err.Error()})
+		return
+	}
+	if oerr != nil {
+		redirectWithOAuthError(w, r, req, oerr)
+		return
+	}
+
+	renderConsentPage(w, http.StatusOK, newConsentPage(req, r.URL.Query()))
+}
+
+// handlerOAuthAuthorizeDecision handles the consent form. Chirpy's API has
+// no cookie sessions, so the user signs in on the form itself; this also
+// means a forged form submission can't approve anything.
+func (cfg *apiConfig) handlerOAuthAuthorizeDecision(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		renderConsentPage(w, http.StatusBadRequest, consentPage{ClientName: "application", Error: "The form couldn't be read"})
+		return
+	}
+	req, oerr, err := cfg.parseAuthorizeRequest(r.Context(), r.PostForm)
+	if err != nil {
+		renderConsentPage(w, http.StatusBadRequest, consentPage{ClientName: "application", Error: err.Error()})
+		return
+	}
+	if oerr != nil {
+		redirectWithOAuthError(w, r, req, oerr)
+		return
+	}
+	if r.PostForm.Get("decision") != "allow" {
+		redirectWithOAuthError(w, r, req, &oauthError{Code: "access_denied", Description: "The user denied the request"})
+		return
+	}
+
+	page := newConsentPage(req, r.PostForm)
+	page.Email = r.PostForm.Get("email")
+	user, err := cfg.checkCredentials(r, page.Email, r.PostForm.Get("password"))
+	var throttled *loginThrottledError
+	if errors.As(err, &throttled) {
+		page.Error = "Too many failed attempts, try again later"
+		renderConsentPage(w, http.StatusTooManyRequests, page)
+		return
+	}
+	if errors.Is(err, errInvalidCredentials) {
+		page.Error = "Incorrect email or password"
+		renderConsentPage(w, http.StatusUnauthorized, page)
+		return
+	}
+	if errors.Is(err, errAccountSuspended) {
+		page.Error = "This account has been suspended"
+		renderConsentPage(w, http.StatusForbidden, page)
+		return
+	}
+	if err == nil && user.DeleteAfter.Valid {
+		page.Error = "This account is closed. Log in to Chirpy to reopen it first"
+		renderConsentPage(w, http.StatusForbidden, page)
+		return
+	}
+	if err != nil {
+		log.Printf("Couldn't check credentials: %s", err)
+		page.Error = "Something went wrong, please try again"
+		renderConsentPage(w, http.StatusInternalServerError, page)
+		return
+	}
+
+	totp, err := cfg.db.GetTOTP(r.Context(), user.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Couldn't check two-factor authentication: %s", err)
+		page.Error = "Something went wrong, please try again"
+		renderConsentPage(w, http.StatusInternalServerError, page)
+		return
+	}
+	if err == nil && totp.ConfirmedAt.Valid {
+		code, recoveryCode := strings.TrimSpace(r.PostForm.Get("code")), ""
+		// TOTP codes are six digits; anything longer is a recovery code.
+		if len(code) > 6 {
+			code, recoveryCode = "", code
+		}
+		err := cfg.checkSecondFactor(r.Context(), totp, code, recoveryCode)
+		if errors.Is(err, errInvalidSecondFactor) {
+			cfg.recordLoginFailure(r.Context(), accountThrottleKey(user.Email), accountThrottle)
+			cfg.recordLoginFailure(r.Context(), ipThrottleKey(clientIP(r)), ipThrottle)
+			page.Error = "Enter a valid two-factor or recovery code"
+			renderConsentPage(w, http.StatusUnauthorized, page)
+			return
+		}
+		if err != nil {
+			log.Printf("Couldn't check two-factor code: %s", err)
+			page.Error = "Something went wrong, please try again"
+			renderConsentPage(w, http.StatusInternalServerError, page)
+			return
+		}
+	}
+	cfg.clearAccountThrottle(r.Context(), user.Email)
+
+	code := auth.MakeToken()
+	err = cfg.db.CreateAuthorizationCode(r.Context(), database.CreateAuthorizationCodeParams{
+		CodeHash:      auth.HashToken(code),
+		ClientID:      req.client.ID,
+		UserID:        user.ID,
+		RedirectUri:   req.redirectURI,
+		Scopes:        req.scopes,
+		CodeChallenge: req.codeChallenge,
+		ExpiresAt:     time.Now().UTC().Add(authorizationCodeTTL),
+	})
+	if err != nil {
+		redirectWithOAuthError(w, r, req, &oauthError{Code: "server_error"})
+		return
+	}
+
+	redirectToClient(w, r, req, url.Values{"code": {code}})
+}
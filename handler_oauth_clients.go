@@ -0,0 +1,184 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mvusic07/Chirpy/internal/auth"
+	"github.com/mvusic07/Chirpy/internal/database"
+)
+
+type OAuthClient struct {
+	ID           uuid.UUID `json:"client_id"`
+	Name         string    `json:"name"`
+	Confidential bool      `json:"confidential"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func newOAuthClient(c database.OauthClient) OAuthClient {
+	return OAuthClient{
+		ID:           c.ID,
+		Name:         c.Name,
+		Confidential: c.SecretHash.Valid,
+		RedirectURIs: c.RedirectUris,
+		Scopes:       c.Scopes,
+		CreatedAt:    c.CreatedAt,
+	}
+}
+
+// validRedirectURI accepts absolute https URIs without a fragment, and http
+// only on the loopback interface for native apps and local development.
+func validRedirectURI(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil || u.Fragment != "" || u.Host == "" {
+		return false
+	}
+	switch u.Scheme {
+	case "https":
+		return true
+	case "http":
+		host := u.Hostname()
+		ip := net.ParseIP(host)
+		return host == "localhost" || (ip != nil && ip.IsLoopback())
+	default:
+		return false
+	}
+}
+
+func (cfg *apiConfig) handlerOAuthClientsCreate(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Name         string   `json:"name"`
+		RedirectURIs []string `json:"redirect_uris"`
+		Scopes       []string `json:"scopes"`
+		Confidential bool     `json:"confidential"`
+	}
+	type response struct {
+		OAuthClient
+		ClientSecret string `json:"client_secret,omitempty"`
+	}
+
+	caller, _ := requestPrincipal(r)
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	var fields []fieldError
+	params.Name = strings.TrimSpace(params.Name)
+	if params.Name == "" || len(params.Name) > 100 {
+		fields = append(fields, fieldError{Field: "name", Code: "invalid", Message: "Name must be between 1 and 100 characters"})
+	}
+	if len(params.RedirectURIs) == 0 {
+		fields = append(fields, fieldError{Field: "redirect_uris", Code: "required", Message: "At least one redirect URI is required"})
+	}
+	for _, uri := range params.RedirectURIs {
+		if !validRedirectURI(uri) {
+			fields = append(fields, fieldError{Field: "redirect_uris", Code: "invalid", Message: "Redirect URIs must be https, or http on localhost, without a fragment: " + uri})
+		}
+	}
+	if len(params.Scopes) == 0 {
+		fields = append(fields, fieldError{Field: "scopes", Code: "required", Message: "At least one scope is required"})
+	}
+	for _, scope := range params.Scopes {
+		if !slices.Contains(knownScopes, scope) {
+			fields = append(fields, fieldError{Field: "scopes", Code: "unknown_scope", Message: "Unknown scope " + scope})
+		}
+	}
+	if len(fields) > 0 {
+		respondWithValidationErrors(w, "Invalid client parameters", fields)
+		return
+	}
+
+	var secret string
+	secretHash := sql.NullString{}
+	if params.Confidential {
+		secret = auth.MakeToken()
+		secretHash = sql.NullString{String: auth.HashToken(secret), Valid: true}
+	}
+	slices.Sort(params.Scopes)
+	client, err := cfg.db.CreateOAuthClient(r.Context(), database.CreateOAuthClientParams{
+		OwnerID:      caller.UserID,
+		Name:         params.Name,
+		SecretHash:   secretHash,
+		RedirectUris: params.RedirectURIs,
+		Scopes:       slices.Compact(params.Scopes),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create client", err)
+		return
+	}
+
+	// This is the only time the secret itself is shown.
+	respondWithJSON(w, http.StatusCreated, response{
+		OAuthClient:  newOAuthClient(client),
+		ClientSecret: secret,
+	})
+}
+
+func (cfg *apiConfig) handlerOAuthClientsList(w http.ResponseWriter, r *http.Request) {
+	caller, _ := requestPrincipal(r)
+
+	clients, err := cfg.db.ListOAuthClients(r.Context(), caller.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't list clients", err)
+		return
+	}
+
+	response := make([]OAuthClient, 0, len(clients))
+	for _, c := range clients {
+		response = append(response, newOAuthClient(c))
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (cfg *apiConfig) handlerOAuthClientsDelete(w http.ResponseWriter, r *http.Request) {
+	caller, _ := requestPrincipal(r)
+
+	clientID, err := uuid.Parse(r.PathValue("clientId"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid client ID", err)
+		return
+	}
+
+	// Deleting the client cascades to its sessions and refresh tokens, but
+	// access tokens already handed out have to be revoked explicitly.
+	sessionIDs, err := cfg.db.ListOAuthClientSessionIDs(r.Context(), uuid.NullUUID{UUID: clientID, Valid: true})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't delete client", err)
+		return
+	}
+	_, err = cfg.db.DeleteOAuthClient(r.Context(), database.DeleteOAuthClientParams{
+		ID:      clientID,
+		OwnerID: caller.UserID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Client not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't delete client", err)
+		return
+	}
+	for _, sessionID := range sessionIDs {
+		if err := cfg.revocations.RevokeSession(r.Context(), sessionID); err != nil {
+			log.Printf("Couldn't revoke access tokens for session %s: %s", sessionID, err)
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
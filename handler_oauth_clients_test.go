@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestValidRedirectURI(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want bool
+	}{
+		{"https://app.example.com/callback", true},
+		{"https://app.example.com/callback?x=1", true},
+		{"http://localhost:3000/callback", true},
+		{"http://127.0.0.1:8765/", true},
+		{"http://[::1]/cb", true},
+		{"http://app.example.com/callback", false},
+		{"https://app.example.com/callback#frag", false},
+		{"javascript:alert(1)", false},
+		{"/relative", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := validRedirectURI(tt.uri); got != tt.want {
+			t.Errorf("validRedirectURI(%q) = %v, want %v", tt.uri, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mvusic07/Chirpy/internal/auth"
+	"github.com/mvusic07/Chirpy/internal/database"
+)
+
+func respondWithOAuthError(w http.ResponseWriter, code int, oerr *oauthError) {
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, code, oerr)
+}
+
+var errInvalidClient = &oauthError{Code: "invalid_client", Description: "Client authentication failed"}
+
+// authenticateClient identifies the client calling a token endpoint, from
+// HTTP Basic credentials or client_id and client_secret form parameters.
+// Confidential clients must present their secret; public clients must not
+// have one to present.
+func (cfg *apiConfig) authenticateClient(r *http.Request) (database.OauthClient, error) {
+	clientIDStr, secret, basic := r.BasicAuth()
+	if basic {
+		// RFC 6749 section 2.3.1: both are form-encoded before Basic encoding.
+		var err error
+		if clientIDStr, err = url.QueryUnescape(clientIDStr); err != nil {
+			return database.OauthClient{}, errInvalidClient
+		}
+		if secret, err = url.QueryUnescape(secret); err != nil {
+			return database.OauthClient{}, errInvalidClient
+		}
+	} else {
+		clientIDStr, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+
+	clientID, err := uuid.Parse(clientIDStr)
+	if err != nil {
+		return database.OauthClient{}, errInvalidClient
+	}
+	client, err := cfg.db.GetOAuthClient(r.Context(), clientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.OauthClient{}, errInvalidClient
+	}
+	if err != nil {
+		return database.OauthClient{}, err
+	}
+	if client.SecretHash.Valid != (secret != "") {
+		return database.OauthClient{}, errInvalidClient
+	}
+	if client.SecretHash.Valid && subtle.ConstantTimeCompare([]byte(auth.HashToken(secret)), []byte(client.SecretHash.String)) != 1 {
+		return database.OauthClient{}, errInvalidClient
+	}
+	return client, nil
+}
+
+// parseTokenRequest reads the form of a request to one of the token
+// endpoints and authenticates the client, responding itself on failure.
+func (cfg *apiConfig) parseTokenRequest(w http.ResponseWriter, r *http.Request) (database.OauthClient, bool) {
+	if err := r.ParseForm(); err != nil {
+		respondWithOAuthError(w, http.StatusBadRequest, &oauthError{Code: "invalid_request", Description: "Couldn't parse form"})
+		return database.OauthClient{}, false
+	}
+	client, err := cfg.authenticateClient(r)
+	var oerr *oauthError
+	if errors.As(err, &oerr) {
+		if _, _, basic := r.BasicAuth(); basic {
+			w.Header().Set("WWW-Authenticate", `Basic realm="chirpy"`)
+		}
+		respondWithOAuthError(w, http.StatusUnauthorized, oerr)
+		return database.OauthClient{}, false
+	}
+	if err != nil {
+		log.Printf("Couldn't authenticate OAuth client: %s", err)
+		respondWithOAuthError(w, http.StatusInternalServerError, &oauthError{Code: "server_error"})
+		return database.OauthClient{}, false
+	}
+	return client, true
+}
+
+type oauthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+}
+
+// handlerOAuthToken is the token endpoint (RFC 6749 section 3.2) for the
+// authorization_code and refresh_token grants.
+func (cfg *apiConfig) handlerOAuthToken(w http.ResponseWriter, r *http.Request) {
+	client, ok := cfg.parseTokenRequest(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		cfg.exchangeAuthorizationCode(w, r, client)
+	case "refresh_token":
+		cfg.refreshOAuthToken(w, r, client)
+	default:
+		respondWithOAuthError(w, http.StatusBadRequest, &oauthError{Code: "unsupported_grant_type"})
+	}
+}
+
+func (cfg *apiConfig) exchangeAuthorizationCode(w http.ResponseWriter, r *http.Request, client database.OauthClient) {
+	errInvalidGrant := &oauthError{Code: "invalid_grant", Description: "The authorization code is invalid or expired"}
+	codeHash := auth.HashToken(r.PostForm.Get("code"))
+
+	tx, err := cfg.conn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithOAuthError(w, http.StatusInternalServerError, &oauthError{Code: "server_error"})
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	code, err := qtx.ConsumeAuthorizationCode(r.Context(), codeHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		tx.Rollback()
+		cfg.detectAuthorizationCodeReuse(r, codeHash)
+		respondWithOAuthError(w, http.StatusBadRequest, errInvalidGrant)
+		return
+	}
+	if err != nil {
+		respondWithOAuthError(w, http.StatusInternalServerError, &oauthError{Code: "server_error"})
+		return
+	}
+	if code.ClientID != client.ID || code.RedirectUri != r.PostForm.Get("redirect_uri") {
+		respondWithOAuthError(w, http.StatusBadRequest, errInvalidGrant)
+		return
+	}
+	if err := auth.VerifyPKCE(r.PostForm.Get("code_verifier"), code.CodeChallenge); err != nil {
+		respondWithOAuthError(w, http.StatusBadRequest, &oauthError{Code: "invalid_grant", Description: "The code_verifier doesn't match"})
+		return
+	}
+	// The user may have been suspended, or closed their account, since
+	// approving the request.
+	user, err := qtx.GetUserByID(r.Context(), code.UserID)
+	if err != nil || user.SuspendedAt.Valid || user.DeleteAfter.Valid {
+		respondWithOAuthError(w, http.StatusBadRequest, errInvalidGrant)
+		return
+	}
+
+	session, err := qtx.CreateClientSession(r.Context(), database.CreateClientSessionParams{
+		UserID:    code.UserID,
+		Name:      client.Name,
+		UserAgent: r.UserAgent(),
+		Ip:        clientIP(r),
+		ClientID:  uuid.NullUUID{UUID: client.ID, Valid: true},
+		Scopes:    code.Scopes,
+	})
+	if err != nil {
+		respondWithOAuthError(w, http.StatusInternalServerError, &oauthError{Code: "server_error"})
+		return
+	}
+	refreshToken, err := createRefreshToken(r.Context(), qtx, code.UserID, session.ID, "")
+	if err != nil {
+		respondWithOAuthError(w, http.StatusInternalServerError, &oauthError{Code: "server_error"})
+		return
+	}
+	err = qtx.SetAuthorizationCodeSession(r.Context(), database.SetAuthorizationCodeSessionParams{
+		CodeHash:  codeHash,
+		SessionID: uuid.NullUUID{UUID: session.ID, Valid: true},
+	})
+	if err != nil {
+		respondWithOAuthError(w, http.StatusInternalServerError, &oauthError{Code: "server_error"})
+		return
+	}
+	accessToken, err := cfg.keys.MakeOAuthJWT(code.UserID, session.ID, client.ID, code.Scopes, accessTokenTTL)
+	if err != nil {
+		respondWithOAuthError(w, http.StatusInternalServerError, &oauthError{Code: "server_error"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithOAuthError(w, http.StatusInternalServerError, &oauthError{Code: "server_error"})
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, oauthTokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(accessTokenTTL.Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        strings.Join(code.Scopes, " "),
+	})
+}
+
+// detectAuthorizationCodeReuse revokes the tokens issued for a code that is
+// presented a second time, as RFC 6749 section 4.1.2 recommends: either the
+// client is broken or the code was intercepted.
+func (cfg *apiConfig) detectAuthorizationCodeReuse(r *http.Request, codeHash string) {
+	code, err := cfg.db.GetAuthorizationCode(r.Context(), codeHash)
+	if err != nil || !code.UsedAt.Valid || !code.SessionID.Valid {
+		return
+	}
+	log.Printf("Authorization code reuse detected for user %s, revoking session %s", code.UserID, code.SessionID.UUID)
+	err = cfg.revokeSession(r.Context(), code.UserID, code.SessionID.UUID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Couldn't revoke session %s: %s", code.SessionID.UUID, err)
+	}
+}
+
+func (cfg *apiConfig) refreshOAuthToken(w http.ResponseWriter, r *http.Request, client database.OauthClient) {
+	errInvalidGrant := &oauthError{Code: "invalid_grant", Description: "The refresh token is invalid or expired"}
+	refreshToken := r.PostForm.Get("refresh_token")
+
+	tx, err := cfg.conn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithOAuthError(w, http.StatusInternalServerError, &oauthError{Code: "server_error"})
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	session, newRefreshToken, err := rotateRefreshToken(r.Context(), qtx, r, refreshToken)
+	if errors.Is(err, sql.ErrNoRows) {
+		tx.Rollback()
+		cfg.detectRefreshTokenReuse(r.Context(), refreshToken)
+		respondWithOAuthError(w, http.StatusBadRequest, errInvalidGrant)
+		return
+	}
+	if err != nil {
+		respondWithOAuthError(w, http.StatusInternalServerError, &oauthError{Code: "server_error"})
+		return
+	}
+	if !session.ClientID.Valid || session.ClientID.UUID != client.ID {
+		respondWithOAuthError(w, http.StatusBadRequest, errInvalidGrant)
+		return
+	}
+
+	// The client may ask for fewer scopes than it was granted, but never more
+	// (RFC 6749 section 6).
+	scopes := session.Scopes
+	if requested := strings.Fields(r.PostForm.Get("scope")); len(requested) > 0 {
+		for _, scope := range requested {
+			if !slices.Contains(session.Scopes, scope) {
+				respondWithOAuthError(w, http.StatusBadRequest, &oauthError{Code: "invalid_scope", Description: "Scope " + scope + " was not granted"})
+				return
+			}
+		}
+		scopes = requested
+	}
+
+	accessToken, err := cfg.keys.MakeOAuthJWT(session.UserID, session.ID, client.ID, scopes, accessTokenTTL)
+	if err != nil {
+		respondWithOAuthError(w, http.StatusInternalServerError, &oauthError{Code: "server_error"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithOAuthError(w, http.StatusInternalServerError, &oauthError{Code: "server_error"})
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, oauthTokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(accessTokenTTL.Seconds()),
+		RefreshToken: newRefreshToken,
+		Scope:        strings.Join(scopes, " "),
+	})
+}
+
+// clientRefreshToken looks up a refresh token issued to client, returning
+// the token and its session.
+func (cfg *apiConfig) clientRefreshToken(r *http.Request, client database.OauthClient, token string) (database.RefreshToken, database.Session, bool) {
+	refreshToken, err := cfg.db.GetRefreshToken(r.Context(), auth.HashRefreshToken(token))
+	if err != nil {
+		return database.RefreshToken{}, database.Session{}, false
+	}
+	session, err := cfg.db.GetSession(r.Context(), refreshToken.FamilyID)
+	if err != nil || !session.ClientID.Valid || session.ClientID.UUID != client.ID {
+		return database.RefreshToken{}, database.Session{}, false
+	}
+	return refreshToken, session, true
+}
+
+// handlerOAuthRevoke implements token revocation (RFC 7009). Revoking a
+// refresh token ends the whole grant; revoking an access token only that
+// token. Unknown tokens are not an error.
+func (cfg *apiConfig) handlerOAuthRevoke(w http.ResponseWriter, r *http.Request) {
+	client, ok := cfg.parseTokenRequest(w, r)
+	if !ok {
+		return
+	}
+	token := r.PostForm.Get("token")
+
+	if _, session, ok := cfg.clientRefreshToken(r, client, token); ok {
+		err := cfg.revokeSession(r.Context(), session.UserID, session.ID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Couldn't revoke session %s: %s", session.ID, err)
+			respondWithOAuthError(w, http.StatusServiceUnavailable, &oauthError{Code: "server_error"})
+			return
+		}
+	} else if claims, err := cfg.keys.ParseOAuthJWT(token); err == nil && claims.ClientUUID() == client.ID {
+		err := cfg.revocations.RevokeToken(r.Context(), claims.TokenID(), claims.ExpiresAt.Time)
+		if err != nil {
+			log.Printf("Couldn't revoke access token: %s", err)
+			respondWithOAuthError(w, http.StatusServiceUnavailable, &oauthError{Code: "server_error"})
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// handlerOAuthIntrospect implements token introspection (RFC 7662). Clients
+// may only introspect their own tokens; anything else is reported inactive.
+func (cfg *apiConfig) handlerOAuthIntrospect(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Active    bool   `json:"active"`
+		Scope     string `json:"scope,omitempty"`
+		ClientID  string `json:"client_id,omitempty"`
+		TokenType string `json:"token_type,omitempty"`
+		Exp       int64  `json:"exp,omitempty"`
+		Iat       int64  `json:"iat,omitempty"`
+		Sub       string `json:"sub,omitempty"`
+		Iss       string `json:"iss,omitempty"`
+		Jti       string `json:"jti,omitempty"`
+	}
+
+	client, ok := cfg.parseTokenRequest(w, r)
+	if !ok {
+		return
+	}
+	token := r.PostForm.Get("token")
+	w.Header().Set("Cache-Control", "no-store")
+
+	if refreshToken, session, ok := cfg.clientRefreshToken(r, client, token); ok {
+		if refreshToken.RevokedAt.Valid || !refreshToken.ExpiresAt.After(time.Now().UTC()) {
+			respondWithJSON(w, http.StatusOK, response{Active: false})
+			return
+		}
+		respondWithJSON(w, http.StatusOK, response{
+			Active:    true,
+			Scope:     strings.Join(session.Scopes, " "),
+			ClientID:  client.ID.String(),
+			TokenType: "refresh_token",
+			Exp:       refreshToken.ExpiresAt.Unix(),
+			Iat:       refreshToken.CreatedAt.Unix(),
+			Sub:       refreshToken.UserID.String(),
+		})
+		return
+	}
+
+	claims, err := cfg.keys.ParseOAuthJWT(token)
+	if err != nil || claims.ClientUUID() != client.ID {
+		respondWithJSON(w, http.StatusOK, response{Active: false})
+		return
+	}
+	respondWithJSON(w, http.StatusOK, response{
+		Active:    true,
+		Scope:     claims.Scope,
+		ClientID:  claims.ClientID,
+		TokenType: "access_token",
+		Exp:       claims.ExpiresAt.Unix(),
+		Iat:       claims.IssuedAt.Unix(),
+		Sub:       claims.Subject,
+		Iss:       claims.Issuer,
+		Jti:       claims.ID,
+	})
+}
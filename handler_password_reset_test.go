@@ -0,0 +1,134 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mvusic07/Chirpy/internal/auth"
+	"github.com/mvusic07/Chirpy/internal/database"
+	"github.com/mvusic07/Chirpy/internal/mailer"
+)
+
+// resetToken asks for a password reset for email and returns the token from
+// the email it sends. The request handler starts the reset in the
+// background, so this starts it directly.
+func (s *testServer) resetToken(t *testing.T, email string) string {
+	t.Helper()
+	s.startPasswordReset(email)
+	files, err := filepath.Glob(filepath.Join(s.mailer.(*mailer.FileMailer).Dir, "*.eml"))
+	if err != nil {
+		t.Fatalf("Couldn't list sent mail: %v", err)
+	}
+	// Glob sorts the files, and their names start with when they were sent.
+	for _, file := range slices.Backward(files) {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Couldn't read %s: %v", file, err)
+		}
+		if strings.Contains(string(data), "Subject: Reset your Chirpy password") {
+			return linkToken(t, string(data))
+		}
+	}
+	t.Fatal("Expected a password reset email")
+	return ""
+}
+
+func TestPasswordReset(t *testing.T) {
+	s := newTestServer(t)
+	session := s.signup(t, "lydia@example.com")
+	other := s.login(t, "lydia@example.com")
+	const newPassword = "a brand new passphrase"
+
+	token := s.resetToken(t, "lydia@example.com")
+	decode(t, s.do(t, "POST", "/api/password-reset/confirm", "", map[string]string{
+		"token":    token,
+		"password": newPassword,
+	}), http.StatusNoContent, nil)
+
+	// Every session that had the old password is signed out.
+	for _, old := range []testSession{session, other} {
+		if rec := s.do(t, "GET", "/api/sessions", old.Token, nil); rec.Code != http.StatusUnauthorized {
+			t.Errorf("Expected the old access token to be revoked, got %d: %s", rec.Code, rec.Body)
+		}
+		if rec := s.do(t, "POST", "/api/refresh", old.RefreshToken, nil); rec.Code != http.StatusUnauthorized {
+			t.Errorf("Expected the old refresh token to be revoked, got %d: %s", rec.Code, rec.Body)
+		}
+	}
+
+	if rec := s.do(t, "POST", "/api/login", "", map[string]string{
+		"email":    "lydia@example.com",
+		"password": testPassword,
+	}); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the old password to be rejected, got %d: %s", rec.Code, rec.Body)
+	}
+	decode(t, s.do(t, "POST", "/api/login", "", map[string]string{
+		"email":    "lydia@example.com",
+		"password": newPassword,
+	}), http.StatusOK, nil)
+}
+
+func TestPasswordReset_TokenIsSingleUse(t *testing.T) {
+	s := newTestServer(t)
+	s.signup(t, "tuco@example.com")
+
+	token := s.resetToken(t, "tuco@example.com")
+	// A rejected password leaves the token usable.
+	if rec := s.do(t, "POST", "/api/password-reset/confirm", "", map[string]string{
+		"token":    token,
+		"password": "short",
+	}); rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected a weak password to be rejected, got %d: %s", rec.Code, rec.Body)
+	}
+	decode(t, s.do(t, "POST", "/api/password-reset/confirm", "", map[string]string{
+		"token":    token,
+		"password": "a brand new passphrase",
+	}), http.StatusNoContent, nil)
+
+	if rec := s.do(t, "POST", "/api/password-reset/confirm", "", map[string]string{
+		"token":    token,
+		"password": "yet another passphrase",
+	}); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected a used token to be rejected, got %d: %s", rec.Code, rec.Body)
+	}
+
+	// Asking again replaces any token still outstanding.
+	stale := s.resetToken(t, "tuco@example.com")
+	s.resetToken(t, "tuco@example.com")
+	if rec := s.do(t, "POST", "/api/password-reset/confirm", "", map[string]string{
+		"token":    stale,
+		"password": "yet another passphrase",
+	}); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected a replaced token to be rejected, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestPasswordReset_Expired(t *testing.T) {
+	s := newTestServer(t)
+	session := s.signup(t, "hector@example.com")
+
+	token := auth.MakeToken()
+	_, err := s.db.CreatePasswordResetToken(t.Context(), database.CreatePasswordResetTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    session.ID,
+		ExpiresAt: time.Now().UTC().Add(-time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("CreatePasswordResetToken failed: %v", err)
+	}
+
+	if rec := s.do(t, "POST", "/api/password-reset/confirm", "", map[string]string{
+		"token":    token,
+		"password": "a brand new passphrase",
+	}); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected an expired token to be rejected, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := s.do(t, "GET", "/api/sessions", session.Token, nil); rec.Code != http.StatusOK {
+		t.Errorf("Expected the session to keep working, got %d: %s", rec.Code, rec.Body)
+	}
+	s.login(t, "hector@example.com")
+}
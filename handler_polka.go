@@ -0,0 +1,160 @@
+package main
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mvusic07/Chirpy/internal/auth"
+	"github.com/mvusic07/Chirpy/internal/database"
+)
+
+const (
+	polkaEventUpgraded   = "user.upgraded"
+	polkaEventDowngraded = "user.downgraded"
+
+	// polkaSignatureTolerance is how far a signed webhook's timestamp may be
+	// from our clock before it is treated as a replay.
+	polkaSignatureTolerance = 5 * time.Minute
+	maxWebhookBodyBytes     = 64 << 10
+)
+
+// isChirpyRed reports whether the user's Chirpy Red membership is active. A
+// membership without an end date lasts until Polka downgrades it.
+func isChirpyRed(u database.User) bool {
+	return chirpyRedActive(u.IsChirpyRed, u.ChirpyRedUntil)
+}
+
+func chirpyRedActive(isChirpyRed bool, until sql.NullTime) bool {
+	return isChirpyRed && (!until.Valid || until.Time.After(time.Now()))
+}
+
+var errInvalidWebhookCredentials = errors.New("invalid webhook credentials")
+
+// authenticatePolka accepts either a Polka-Signature HMAC over the body or,
+// from senders that can't sign, the shared key in an ApiKey header. Signed
+// requests are preferred: the key alone doesn't stop a captured request from
+// being replayed, only the event ID check does.
+func (cfg *apiConfig) authenticatePolka(r *http.Request, body []byte) error {
+	if signature := r.Header.Get("Polka-Signature"); signature != "" {
+		if cfg.polkaWebhookSecret == "" {
+			return errInvalidWebhookCredentials
+		}
+		return auth.VerifyWebhookSignature(cfg.polkaWebhookSecret, signature, body, time.Now(), polkaSignatureTolerance)
+	}
+	if cfg.polkaKey == "" {
+		return errInvalidWebhookCredentials
+	}
+	key, err := auth.GetAPIKey(r.Header)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare([]byte(key), []byte(cfg.polkaKey)) != 1 {
+		return errInvalidWebhookCredentials
+	}
+	return nil
+}
+
+// handlerPolkaWebhooks applies Chirpy Red membership changes from Polka.
+// Polka retries anything but a 2xx, so events we don't handle are still
+// acknowledged, and redelivered events are acknowledged without being applied
+// again.
+func (cfg *apiConfig) handlerPolkaWebhooks(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		ID    string `json:"id"`
+		Event string `json:"event"`
+		Data  struct {
+			UserID    uuid.UUID  `json:"user_id"`
+			PeriodEnd *time.Time `json:"period_end"`
+		} `json:"data"`
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodyBytes))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't read request body", err)
+		return
+	}
+	if err := cfg.authenticatePolka(r, body); err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't authenticate webhook", err)
+		return
+	}
+
+	params := parameters{}
+	if err := json.Unmarshal(body, &params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+	if params.Event != polkaEventUpgraded && params.Event != polkaEventDowngraded {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	// The ID is what stops a captured event from being replayed.
+	if params.ID == "" {
+		respondWithError(w, http.StatusBadRequest, "Event has no id", nil)
+		return
+	}
+
+	tx, err := cfg.conn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	_, err = qtx.GetUserByID(r.Context(), params.Data.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't find user", err)
+		return
+	}
+
+	n, err := qtx.RecordPolkaEvent(r.Context(), database.RecordPolkaEventParams{
+		ID:     params.ID,
+		Event:  params.Event,
+		UserID: params.Data.UserID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't record event", err)
+		return
+	}
+	if n == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	switch params.Event {
+	case polkaEventUpgraded:
+		var until sql.NullTime
+		if params.Data.PeriodEnd != nil {
+			until = sql.NullTime{Time: params.Data.PeriodEnd.UTC(), Valid: true}
+		}
+		_, err = qtx.UpgradeToChirpyRed(r.Context(), database.UpgradeToChirpyRedParams{
+			ID:             params.Data.UserID,
+			ChirpyRedUntil: until,
+		})
+	case polkaEventDowngraded:
+		_, err = qtx.DowngradeFromChirpyRed(r.Context(), params.Data.UserID)
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update membership", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update membership", err)
+		return
+	}
+	log.Printf("Applied Polka %s event for user %s", params.Event, params.Data.UserID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mvusic07/Chirpy/internal/auth"
+)
+
+func TestHandlerPolkaWebhooksAuth(t *testing.T) {
+	cfg := &apiConfig{polkaKey: "f271c81ff7084ee5b99a5091b42d486e", polkaWebhookSecret: "whsec_test"}
+	// An event we don't handle is acknowledged once authenticated, without
+	// touching the database.
+	body := `{"id":"evt_1","event":"user.payment_failed","data":{"user_id":"3311741c-680c-4546-99f3-fc9efac2036c"}}`
+
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		wantStatus int
+	}{
+		{name: "no credentials", wantStatus: http.StatusUnauthorized},
+		{name: "api key", headers: map[string]string{"Authorization": "ApiKey " + cfg.polkaKey}, wantStatus: http.StatusNoContent},
+		{name: "wrong api key", headers: map[string]string{"Authorization": "ApiKey nope"}, wantStatus: http.StatusUnauthorized},
+		{name: "bearer instead of api key", headers: map[string]string{"Authorization": "Bearer " + cfg.polkaKey}, wantStatus: http.StatusUnauthorized},
+		{name: "signature", headers: map[string]string{"Polka-Signature": auth.SignWebhook(cfg.polkaWebhookSecret, []byte(body), time.Now())}, wantStatus: http.StatusNoContent},
+		{name: "replayed signature", headers: map[string]string{"Polka-Signature": auth.SignWebhook(cfg.polkaWebhookSecret, []byte(body), time.Now().Add(-time.Hour))}, wantStatus: http.StatusUnauthorized},
+		// A bad signature isn't rescued by a good API key.
+		{name: "bad signature with api key", headers: map[string]string{"Polka-Signature": "t=1,v1=00", "Authorization": "ApiKey " + cfg.polkaKey}, wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/polka/webhooks", strings.NewReader(body))
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			cfg.handlerPolkaWebhooks(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandlerPolkaWebhooks_RequiresEventID(t *testing.T) {
+	cfg := &apiConfig{polkaKey: "f271c81ff7084ee5b99a5091b42d486e"}
+	// Rejected before the database is touched.
+	body := `{"event":"user.upgraded","data":{"user_id":"3311741c-680c-4546-99f3-fc9efac2036c"}}`
+
+	req := httptest.NewRequest(http.MethodPost, "/api/polka/webhooks", strings.NewReader(body))
+	req.Header.Set("Authorization", "ApiKey "+cfg.polkaKey)
+	rec := httptest.NewRecorder()
+	cfg.handlerPolkaWebhooks(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusBadRequest, rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandlerPolkaWebhooks_PeriodEndUTC(t *testing.T) {
+	s := newTestServer(t)
+	s.polkaKey = "f271c81ff7084ee5b99a5091b42d486e"
+	session := s.signup(t, "saul@example.com")
+
+	body := map[string]any{
+		"id":    "evt_1",
+		"event": polkaEventUpgraded,
+		"data": map[string]any{
+			"user_id":    session.ID,
+			"period_end": "2099-01-01T12:00:00+02:00",
+		},
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/polka/webhooks", bytes.NewReader(data))
+	req.Header.Set("Authorization", "ApiKey "+s.polkaKey)
+	rec := httptest.NewRecorder()
+	s.handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusNoContent, rec.Code, rec.Body.String())
+	}
+
+	user, err := s.db.GetUserByID(t.Context(), session.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID failed: %v", err)
+	}
+	want := time.Date(2099, 1, 1, 10, 0, 0, 0, time.UTC)
+	if !user.ChirpyRedUntil.Valid || !user.ChirpyRedUntil.Time.Equal(want) {
+		t.Errorf("Expected membership until %v, got %v", want, user.ChirpyRedUntil)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/mvusic07/Chirpy/internal/database"
+	"github.com/mvusic07/Chirpy/internal/mailer"
+)
+
+func (cfg *apiConfig) handlerProfileGet(w http.ResponseWriter, r *http.Request) {
+	handle := strings.TrimPrefix(r.PathValue("handle"), "@")
+
+	user, err := cfg.db.GetUserByHandle(r.Context(), handle)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't find user", err)
+		return
+	}
+	// Closed and suspended accounts are hidden, as if they were gone.
+	if user.DeleteAfter.Valid || user.SuspendedAt.Valid {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", nil)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, newProfile(user))
+}
+
+// handlerProfileUpdate changes only the profile fields present in the body;
+// an empty string clears a field. A new email or password can be set too,
+// but only from a login session and with the current password; a new email
+// takes effect once confirmed, as with PUT /api/users.
+func (cfg *apiConfig) handlerProfileUpdate(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Handle      *string `json:"handle"`
+		DisplayName *string `json:"display_name"`
+		Bio         *string `json:"bio"`
+		AvatarURL   *string `json:"avatar_url"`
+		Location    *string `json:"location"`
+
+		Email           *string `json:"email"`
+		Password        *string `json:"password"`
+		CurrentPassword string  `json:"current_password"`
+	}
+
+	caller, _ := requestPrincipal(r)
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	var fields []fieldError
+	if params.Handle != nil {
+		*params.Handle = strings.TrimPrefix(*params.Handle, "@")
+		if err := validateHandle(*params.Handle); err != nil {
+			fields = append(fields, fieldError{Field: "handle", Code: "invalid", Message: err.Error()})
+		}
+	}
+	if params.DisplayName != nil && tooLong(*params.DisplayName, maxDisplayNameLength) {
+		fields = append(fields, fieldError{Field: "display_name", Code: "too_long", Message: "Display name is too long"})
+	}
+	if params.Bio != nil && tooLong(*params.Bio, maxBioLength) {
+		fields = append(fields, fieldError{Field: "bio", Code: "too_long", Message: "Bio is too long"})
+	}
+	if params.Location != nil && tooLong(*params.Location, maxLocationLength) {
+		fields = append(fields, fieldError{Field: "location", Code: "too_long", Message: "Location is too long"})
+	}
+	if params.AvatarURL != nil {
+		if err := validateAvatarURL(*params.AvatarURL); err != nil {
+			fields = append(fields, fieldError{Field: "avatar_url", Code: "invalid", Message: err.Error()})
+		}
+	}
+	if len(fields) > 0 {
+		respondWithValidationErrors(w, "Invalid profile", fields)
+		return
+	}
+
+	credentialsChanged := params.Email != nil || params.Password != nil
+	if credentialsChanged && caller.Delegated() {
+		respondWithError(w, http.StatusForbidden, "Changing the email or password requires a login session", nil)
+		return
+	}
+	var current database.User
+	var email, hashedPassword string
+	if credentialsChanged {
+		current, err = cfg.db.GetUserByID(r.Context(), caller.UserID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't find user", err)
+			return
+		}
+		email = current.Email
+		if params.Email != nil {
+			var ok bool
+			if email, ok = normalizeEmailParam(w, *params.Email); !ok {
+				return
+			}
+		}
+		if !cfg.confirmPassword(w, r, current, params.CurrentPassword) {
+			return
+		}
+		if params.Password != nil {
+			if !cfg.checkNewPassword(w, *params.Password, current.Email, email) {
+				return
+			}
+			hashedPassword, err = cfg.passwords.Hash(*params.Password)
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Couldn't hash password", err)
+				return
+			}
+		}
+	}
+
+	// The profile and credentials are saved together, so a request that
+	// fails on the email or password leaves the profile as it was.
+	tx, err := cfg.conn.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	user, err := qtx.UpdateProfile(r.Context(), database.UpdateProfileParams{
+		ID:          caller.UserID,
+		Handle:      nullString(params.Handle),
+		DisplayName: nullString(params.DisplayName),
+		Bio:         nullString(params.Bio),
+		AvatarUrl:   nullString(params.AvatarURL),
+		Location:    nullString(params.Location),
+	})
+	if isUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "Handle is already taken", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update profile", err)
+		return
+	}
+
+	var verification *mailer.Message
+	if params.Email != nil {
+		user, verification, err = cfg.requestEmailChange(r.Context(), qtx, user, email)
+		if errors.Is(err, errEmailInUse) {
+			respondWithError(w, http.StatusConflict, "Email is already in use by another account", err)
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't update email", err)
+			return
+		}
+	}
+	if params.Password != nil {
+		user, err = qtx.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
+			ID:             caller.UserID,
+			HashedPassword: hashedPassword,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't update password", err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't update profile", err)
+		return
+	}
+
+	if verification != nil {
+		cfg.sendEmail(*verification)
+	}
+	if params.Password != nil {
+		if err := cfg.revokeAllSessions(r.Context(), caller.UserID); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't revoke sessions", err)
+			return
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, newUser(user))
+}
+
+// nullString maps an omitted JSON field to NULL, which the update query
+// leaves unchanged.
+func nullString(s *string) sql.NullString {
+	if s == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *s, Valid: true}
+}
@@ -1,15 +1,70 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
 	"net/http"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/mvusic07/Chirpy/internal/auth"
+	"github.com/mvusic07/Chirpy/internal/database"
 )
 
+const (
+	accessTokenTTL  = time.Hour
+	refreshTokenTTL = time.Hour * 24 * 60
+)
+
+// createRefreshToken mints a refresh token in the given family. Each login
+// session is one family; every rotation adds a child that points at the token
+// it replaced.
+// Only the digest is stored, the raw token is returned to hand to the client.
+func createRefreshToken(ctx context.Context, q *database.Queries, userID, familyID uuid.UUID, parentHash string) (string, error) {
+	refreshToken := auth.MakeRefreshToken()
+	_, err := q.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		TokenHash:       auth.HashRefreshToken(refreshToken),
+		UserID:          userID,
+		ExpiresAt:       time.Now().UTC().Add(refreshTokenTTL),
+		FamilyID:        familyID,
+		ParentTokenHash: sql.NullString{String: parentHash, Valid: parentHash != ""},
+	})
+	if err != nil {
+		return "", err
+	}
+	return refreshToken, nil
+}
+
+// rotateRefreshToken revokes refreshToken and issues its successor in the
+// same family, returning the session the family belongs to. q should be in a
+// transaction so that nothing is rotated unless the caller commits.
+// sql.ErrNoRows means the token is unknown, expired or already used.
+func rotateRefreshToken(ctx context.Context, q *database.Queries, r *http.Request, refreshToken string) (database.Session, string, error) {
+	old, err := q.RotateRefreshToken(ctx, auth.HashRefreshToken(refreshToken))
+	if err != nil {
+		return database.Session{}, "", err
+	}
+	newRefreshToken, err := createRefreshToken(ctx, q, old.UserID, old.FamilyID, old.TokenHash)
+	if err != nil {
+		return database.Session{}, "", err
+	}
+	session, err := q.TouchSession(ctx, database.TouchSessionParams{
+		ID:        old.FamilyID,
+		UserAgent: r.UserAgent(),
+		Ip:        clientIP(r),
+	})
+	if err != nil {
+		return database.Session{}, "", err
+	}
+	return session, newRefreshToken, nil
+}
+
 func (cfg *apiConfig) handlerRefresh(w http.ResponseWriter, r *http.Request) {
 	type response struct {
-		Token string `json:"token"`
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
 	}
 
 	refreshToken, err := auth.GetBearerToken(r.Header)
@@ -18,27 +73,76 @@ func (cfg *apiConfig) handlerRefresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := cfg.db.GetUserFromRefreshToken(r.Context(), refreshToken)
+	tx, err := cfg.conn.BeginTx(r.Context(), nil)
 	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	session, newRefreshToken, err := rotateRefreshToken(r.Context(), qtx, r, refreshToken)
+	if errors.Is(err, sql.ErrNoRows) {
+		tx.Rollback()
+		cfg.detectRefreshTokenReuse(r.Context(), refreshToken)
 		respondWithError(w, http.StatusUnauthorized, "Couldn't get user for refresh token", err)
 		return
 	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't rotate refresh token", err)
+		return
+	}
+	// Tokens issued to third-party apps carry limited scopes, which this
+	// endpoint would throw away.
+	if session.ClientID.Valid {
+		respondWithError(w, http.StatusUnauthorized, "Refresh this token through /oauth/token", nil)
+		return
+	}
 
-	accessToken, err := auth.MakeJWT(
-		user.ID,
-		cfg.tokenSecret,
-		time.Hour,
-	)
+	// The role is read afresh, so a refresh picks up role changes.
+	user, err := qtx.GetUserByID(r.Context(), session.UserID)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		respondWithError(w, http.StatusInternalServerError, "Couldn't get user for refresh token", err)
+		return
+	}
+	if user.SuspendedAt.Valid {
+		respondWithError(w, http.StatusForbidden, "This account has been suspended", errAccountSuspended)
+		return
+	}
+
+	accessToken, err := cfg.keys.MakeRoleJWT(user.ID, session.ID, user.Role, accessTokenTTL)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create access JWT", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't save refresh token", err)
 		return
 	}
 
 	respondWithJSON(w, http.StatusOK, response{
-		Token: accessToken,
+		Token:        accessToken,
+		RefreshToken: newRefreshToken,
 	})
 }
 
+// detectRefreshTokenReuse is called when a refresh token could not be rotated.
+// A token that exists but was already revoked has either been rotated or
+// logged out, so presenting it again means it leaked: revoke the whole family
+// so neither the attacker nor the victim can keep using it.
+func (cfg *apiConfig) detectRefreshTokenReuse(ctx context.Context, refreshToken string) {
+	token, err := cfg.db.GetRefreshToken(ctx, auth.HashRefreshToken(refreshToken))
+	if err != nil || !token.RevokedAt.Valid {
+		return
+	}
+	log.Printf("Refresh token reuse detected for user %s, revoking session %s", token.UserID, token.FamilyID)
+	err = cfg.revokeSession(ctx, token.UserID, token.FamilyID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Couldn't revoke session %s: %s", token.FamilyID, err)
+	}
+}
+
 func (cfg *apiConfig) handlerRevoke(w http.ResponseWriter, r *http.Request) {
 	refreshToken, err := auth.GetBearerToken(r.Header)
 	if err != nil {
@@ -46,11 +150,17 @@ func (cfg *apiConfig) handlerRevoke(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = cfg.db.RevokeRefreshToken(r.Context(), refreshToken)
+	token, err := cfg.db.RevokeRefreshToken(r.Context(), auth.HashRefreshToken(refreshToken))
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke session", err)
 		return
 	}
 
+	err = cfg.revokeSession(r.Context(), token.UserID, token.FamilyID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke session", err)
+		return
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
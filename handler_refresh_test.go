@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRefresh_RotatesToken(t *testing.T) {
+	s := newTestServer(t)
+	session := s.signup(t, "saul@example.com")
+
+	var rotated testSession
+	decode(t, s.do(t, "POST", "/api/refresh", session.RefreshToken, nil), http.StatusOK, &rotated)
+	if rotated.RefreshToken == "" || rotated.RefreshToken == session.RefreshToken {
+		t.Fatalf("Expected a new refresh token, got %q", rotated.RefreshToken)
+	}
+	if rotated.Token == "" {
+		t.Fatal("Expected an access token")
+	}
+
+	if rec := s.do(t, "POST", "/api/refresh", rotated.RefreshToken, nil); rec.Code != http.StatusOK {
+		t.Errorf("Expected the new refresh token to work, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestRefresh_RotatedTokenIsInvalid(t *testing.T) {
+	s := newTestServer(t)
+	session := s.signup(t, "kim@example.com")
+
+	decode(t, s.do(t, "POST", "/api/refresh", session.RefreshToken, nil), http.StatusOK, nil)
+	if rec := s.do(t, "POST", "/api/refresh", session.RefreshToken, nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the rotated refresh token to be rejected, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestRefresh_ReuseRevokesFamily(t *testing.T) {
+	s := newTestServer(t)
+	session := s.signup(t, "howard@example.com")
+
+	var rotated testSession
+	decode(t, s.do(t, "POST", "/api/refresh", session.RefreshToken, nil), http.StatusOK, &rotated)
+
+	// Presenting the old token again means it leaked.
+	if rec := s.do(t, "POST", "/api/refresh", session.RefreshToken, nil); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected reuse to be rejected, got %d: %s", rec.Code, rec.Body)
+	}
+
+	if rec := s.do(t, "POST", "/api/refresh", rotated.RefreshToken, nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the rest of the family to be revoked, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := s.do(t, "GET", "/api/sessions", rotated.Token, nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the family's access token to be revoked, got %d: %s", rec.Code, rec.Body)
+	}
+
+	// Other sessions are unaffected.
+	other := s.login(t, "howard@example.com")
+	if rec := s.do(t, "POST", "/api/refresh", other.RefreshToken, nil); rec.Code != http.StatusOK {
+		t.Errorf("Expected another session to keep working, got %d: %s", rec.Code, rec.Body)
+	}
+}
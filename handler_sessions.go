@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mvusic07/Chirpy/internal/database"
+)
+
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	Name       string    `json:"name"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	Current    bool      `json:"current"`
+	// ClientID and Scopes are set for sessions granted to an OAuth client.
+	ClientID *uuid.UUID `json:"client_id,omitempty"`
+	Scopes   []string   `json:"scopes,omitempty"`
+}
+
+func newSession(s database.Session, current uuid.UUID) Session {
+	var clientID *uuid.UUID
+	if s.ClientID.Valid {
+		clientID = &s.ClientID.UUID
+	}
+	return Session{
+		ID:         s.ID,
+		Name:       s.Name,
+		UserAgent:  s.UserAgent,
+		IP:         s.Ip,
+		CreatedAt:  s.CreatedAt,
+		LastUsedAt: s.LastUsedAt,
+		Current:    s.ID == current,
+		ClientID:   clientID,
+		Scopes:     s.Scopes,
+	}
+}
+
+// clientIP returns the address of the peer that made the request.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// revokeSession signs a single session out by revoking it together with every
+// refresh token in its family and every access token issued for it.
+func (cfg *apiConfig) revokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	tx, err := cfg.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	_, err = qtx.RevokeSession(ctx, database.RevokeSessionParams{
+		ID:     sessionID,
+		UserID: userID,
+	})
+	if err != nil {
+		return err
+	}
+	if err := qtx.RevokeRefreshTokenFamily(ctx, sessionID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return cfg.revocations.RevokeSession(ctx, sessionID)
+}
+
+// revokeAllSessions signs the user out everywhere, including access tokens
+// that have not expired yet.
+func (cfg *apiConfig) revokeAllSessions(ctx context.Context, userID uuid.UUID) error {
+	tx, err := cfg.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := cfg.db.WithTx(tx)
+
+	if err := qtx.RevokeAllSessions(ctx, userID); err != nil {
+		return err
+	}
+	if err := qtx.RevokeAllRefreshTokens(ctx, userID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return cfg.revocations.RevokeUser(ctx, userID)
+}
+
+func (cfg *apiConfig) handlerSessionsList(w http.ResponseWriter, r *http.Request) {
+	caller, _ := requestPrincipal(r)
+	userID := caller.UserID
+
+	dbSessions, err := cfg.db.ListActiveSessions(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve sessions", err)
+		return
+	}
+
+	sessions := []Session{}
+	for _, dbSession := range dbSessions {
+		sessions = append(sessions, newSession(dbSession, caller.SessionID))
+	}
+
+	respondWithJSON(w, http.StatusOK, sessions)
+}
+
+func (cfg *apiConfig) handlerSessionsRename(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Name string `json:"name"`
+	}
+
+	caller, _ := requestPrincipal(r)
+	userID := caller.UserID
+
+	sessionID, err := uuid.Parse(r.PathValue("sessionId"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid session ID", err)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	session, err := cfg.db.RenameSession(r.Context(), database.RenameSessionParams{
+		ID:     sessionID,
+		UserID: userID,
+		Name:   params.Name,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Session not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't rename session", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, newSession(session, caller.SessionID))
+}
+
+func (cfg *apiConfig) handlerSessionsRevoke(w http.ResponseWriter, r *http.Request) {
+	caller, _ := requestPrincipal(r)
+	userID := caller.UserID
+
+	sessionID, err := uuid.Parse(r.PathValue("sessionId"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid session ID", err)
+		return
+	}
+
+	err = cfg.revokeSession(r.Context(), userID, sessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Session not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke session", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *apiConfig) handlerSessionsRevokeAll(w http.ResponseWriter, r *http.Request) {
+	caller, _ := requestPrincipal(r)
+	userID := caller.UserID
+
+	err := cfg.revokeAllSessions(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke sessions", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
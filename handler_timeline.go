@@ -0,0 +1,33 @@
+package main
+
+import "net/http"
+
+// handlerTimeline serves the caller's home timeline, newest first.
+func (cfg *apiConfig) handlerTimeline(w http.ResponseWriter, r *http.Request) {
+	caller, _ := requestPrincipal(r)
+	page, err := parseFeedPageParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	timeline, err := cfg.timelineFor(r.Context(), caller.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve timeline", err)
+		return
+	}
+
+	dbChirps, err := timeline.Page(r.Context(), caller.UserID, page.Cursor, page.fetchLimit())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve timeline", err)
+		return
+	}
+	dbChirps = trimPage(w, r, page, dbChirps, chirpCursor)
+
+	chirps := newChirps(dbChirps)
+	if err := cfg.decorateChirps(r, chirps); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve timeline", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, chirps)
+}
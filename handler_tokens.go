@@ -0,0 +1,150 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mvusic07/Chirpy/internal/auth"
+	"github.com/mvusic07/Chirpy/internal/database"
+)
+
+type PersonalAccessToken struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+func newPersonalAccessToken(t database.PersonalAccessToken) PersonalAccessToken {
+	return PersonalAccessToken{
+		ID:         t.ID,
+		Name:       t.Name,
+		Scopes:     t.Scopes,
+		CreatedAt:  t.CreatedAt,
+		ExpiresAt:  nullTimePtr(t.ExpiresAt),
+		LastUsedAt: nullTimePtr(t.LastUsedAt),
+	}
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func (cfg *apiConfig) handlerTokensCreate(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Name      string     `json:"name"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	type response struct {
+		PersonalAccessToken
+		Token string `json:"token"`
+	}
+
+	caller, _ := requestPrincipal(r)
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	var fields []fieldError
+	params.Name = strings.TrimSpace(params.Name)
+	if params.Name == "" || len(params.Name) > 100 {
+		fields = append(fields, fieldError{Field: "name", Code: "invalid", Message: "Name must be between 1 and 100 characters"})
+	}
+	if len(params.Scopes) == 0 {
+		fields = append(fields, fieldError{Field: "scopes", Code: "required", Message: "At least one scope is required"})
+	}
+	for _, scope := range params.Scopes {
+		if !slices.Contains(knownScopes, scope) {
+			fields = append(fields, fieldError{Field: "scopes", Code: "unknown_scope", Message: "Unknown scope " + scope})
+		}
+	}
+	if params.ExpiresAt != nil && !params.ExpiresAt.After(time.Now()) {
+		fields = append(fields, fieldError{Field: "expires_at", Code: "in_past", Message: "Expiry must be in the future"})
+	}
+	if len(fields) > 0 {
+		respondWithValidationErrors(w, "Invalid token parameters", fields)
+		return
+	}
+
+	slices.Sort(params.Scopes)
+	expiresAt := sql.NullTime{}
+	if params.ExpiresAt != nil {
+		expiresAt = sql.NullTime{Time: params.ExpiresAt.UTC(), Valid: true}
+	}
+	token := auth.MakePersonalAccessToken()
+	pat, err := cfg.db.CreatePersonalAccessToken(r.Context(), database.CreatePersonalAccessTokenParams{
+		UserID:    caller.UserID,
+		Name:      params.Name,
+		TokenHash: auth.HashToken(token),
+		Scopes:    slices.Compact(params.Scopes),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create token", err)
+		return
+	}
+
+	// This is the only time the token itself is shown.
+	respondWithJSON(w, http.StatusCreated, response{
+		PersonalAccessToken: newPersonalAccessToken(pat),
+		Token:               token,
+	})
+}
+
+func (cfg *apiConfig) handlerTokensList(w http.ResponseWriter, r *http.Request) {
+	caller, _ := requestPrincipal(r)
+
+	tokens, err := cfg.db.ListPersonalAccessTokens(r.Context(), caller.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't list tokens", err)
+		return
+	}
+
+	response := make([]PersonalAccessToken, 0, len(tokens))
+	for _, t := range tokens {
+		response = append(response, newPersonalAccessToken(t))
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (cfg *apiConfig) handlerTokensRevoke(w http.ResponseWriter, r *http.Request) {
+	caller, _ := requestPrincipal(r)
+
+	tokenID, err := uuid.Parse(r.PathValue("tokenId"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid token ID", err)
+		return
+	}
+
+	_, err = cfg.db.RevokePersonalAccessToken(r.Context(), database.RevokePersonalAccessTokenParams{
+		ID:     tokenID,
+		UserID: caller.UserID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Token not found", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't revoke token", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestUpdateUser_RequiresSession(t *testing.T) {
+	s := newTestServer(t)
+	session := s.signup(t, "kim@example.com")
+	pat := s.personalAccessToken(t, session, scopeProfileWrite)
+
+	rec := s.do(t, "PUT", "/api/users", pat, map[string]string{
+		"email":    "kim@example.org",
+		"password": testPassword,
+	})
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a personal access token to be refused, got %d: %s", rec.Code, rec.Body)
+	}
+
+	// profile:write still covers the profile itself.
+	rec = s.do(t, "PATCH", "/api/me", pat, map[string]string{"bio": "Lawyer"})
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected the profile update to succeed, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = s.do(t, "PUT", "/api/users", session.Token, map[string]string{
+		"email":            "kim@example.org",
+		"password":         testPassword,
+		"current_password": testPassword,
+	})
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected a login session to change the email, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestUpdateUser_RequiresCurrentPassword(t *testing.T) {
+	s := newTestServer(t)
+	session := s.signup(t, "chuck@example.com")
+
+	for _, current := range []string{"", "not the password"} {
+		rec := s.do(t, "PUT", "/api/users", session.Token, map[string]string{
+			"email":            "mallory@example.com",
+			"password":         "a whole new password for mallory",
+			"current_password": current,
+		})
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("Expected current password %q to be refused, got %d: %s", current, rec.Code, rec.Body)
+		}
+	}
+
+	// Nothing changed: the old password still logs in.
+	s.login(t, "chuck@example.com")
+}
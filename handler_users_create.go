@@ -3,19 +3,50 @@ package main
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/mvusic07/Chirpy/internal/auth"
 	"github.com/mvusic07/Chirpy/internal/database"
 )
 
 type User struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Email     string    `json:"email"`
-	Password  string    `json:"-"`
+	ID             uuid.UUID  `json:"id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	Email          string     `json:"email"`
+	EmailVerified  bool       `json:"email_verified"`
+	PendingEmail   string     `json:"pending_email,omitempty"`
+	Handle         string     `json:"handle"`
+	DisplayName    string     `json:"display_name"`
+	Bio            string     `json:"bio"`
+	AvatarURL      string     `json:"avatar_url"`
+	Location       string     `json:"location"`
+	Role           string     `json:"role"`
+	IsChirpyRed    bool       `json:"is_chirpy_red"`
+	ChirpyRedUntil *time.Time `json:"chirpy_red_until,omitempty"`
+	DeleteAfter    *time.Time `json:"delete_after,omitempty"`
+	Password       string     `json:"-"`
+}
+
+func newUser(u database.User) User {
+	return User{
+		ID:             u.ID,
+		CreatedAt:      u.CreatedAt,
+		UpdatedAt:      u.UpdatedAt,
+		Email:          u.Email,
+		EmailVerified:  u.EmailVerifiedAt.Valid,
+		PendingEmail:   u.PendingEmail.String,
+		Handle:         u.Handle,
+		DisplayName:    u.DisplayName,
+		Bio:            u.Bio,
+		AvatarURL:      u.AvatarUrl,
+		Location:       u.Location,
+		Role:           u.Role,
+		IsChirpyRed:    isChirpyRed(u),
+		ChirpyRedUntil: nullTimePtr(u.ChirpyRedUntil),
+		DeleteAfter:    nullTimePtr(u.DeleteAfter),
+	}
 }
 
 func (apiCfg *apiConfig) handlerUsersCreate(w http.ResponseWriter, r *http.Request) {
@@ -23,6 +54,7 @@ func (apiCfg *apiConfig) handlerUsersCreate(w http.ResponseWriter, r *http.Reque
 	type parametri struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		Handle   string `json:"handle"`
 	}
 	type response struct {
 		User
@@ -35,28 +67,54 @@ func (apiCfg *apiConfig) handlerUsersCreate(w http.ResponseWriter, r *http.Reque
 		respondWithError(w, http.StatusInternalServerError, "Couldn't decode the parameters", err)
 		return
 	}
-	hashedPassword, er := auth.HashPassword(params.Password)
+	email, ok := normalizeEmailParam(w, params.Email)
+	if !ok {
+		return
+	}
+	// The handle is optional at signup; without one the account gets a
+	// placeholder to change later.
+	handle := strings.TrimPrefix(params.Handle, "@")
+	if handle == "" {
+		handle = generateHandle()
+	} else if err := validateHandle(handle); err != nil {
+		respondWithValidationErrors(w, "Invalid handle", []fieldError{{Field: "handle", Code: "invalid", Message: err.Error()}})
+		return
+	}
+	if !apiCfg.checkNewPassword(w, params.Password, email) {
+		return
+	}
+	hashedPassword, er := apiCfg.passwords.Hash(params.Password)
 	if er != nil {
 		respondWithError(w, http.StatusInternalServerError, "Problem with hashing", err)
 		return
 	}
 
 	dbuser, err := apiCfg.db.CreateUser(r.Context(), database.CreateUserParams{
-		Email:          params.Email,
+		Email:          email,
 		HashedPassword: hashedPassword,
+		Handle:         handle,
 	})
+	if isUniqueViolation(err, "users_handle_lower_idx") {
+		respondWithError(w, http.StatusConflict, "Handle is already taken", err)
+		return
+	}
+	if isUniqueViolation(err) {
+		respondWithError(w, http.StatusConflict, "An account with this email already exists", err)
+		return
+	}
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Couldn't create user", err)
 		return
 	}
 
+	err = apiCfg.startEmailVerification(r.Context(), dbuser.ID, dbuser.Email)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't start email verification", err)
+		return
+	}
+
 	respondWithJSON(w, http.StatusCreated, response{
-		User: User{
-			ID:        dbuser.ID,
-			CreatedAt: dbuser.CreatedAt,
-			UpdatedAt: dbuser.UpdatedAt,
-			Email:     dbuser.Email,
-		},
+		User: newUser(dbuser),
 	})
 
 }
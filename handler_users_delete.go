@@ -0,0 +1,74 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/mvusic07/Chirpy/internal/database"
+)
+
+// handlerUsersDelete closes the caller's account. With no grace period it is
+// deleted straight away; otherwise it is signed out everywhere and deleted
+// once the period is over, unless its owner logs in again first.
+func (cfg *apiConfig) handlerUsersDelete(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Password string `json:"password"`
+	}
+	type response struct {
+		DeleteAfter time.Time `json:"delete_after"`
+	}
+
+	caller, _ := requestPrincipal(r)
+	userID := caller.UserID
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Couldn't decode parameters", err)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Couldn't find user", err)
+		return
+	}
+
+	if !cfg.confirmPassword(w, r, user, params.Password) {
+		return
+	}
+
+	if cfg.accountDeletionGrace == 0 {
+		if _, err := cfg.db.DeleteUser(r.Context(), userID); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't delete account", err)
+			return
+		}
+		// The user is gone, but their access tokens are still signed.
+		if err := cfg.revocations.RevokeUser(r.Context(), userID); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't revoke access tokens", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	user, err = cfg.db.ScheduleUserDeletion(r.Context(), database.ScheduleUserDeletionParams{
+		ID:          userID,
+		DeleteAfter: sql.NullTime{Time: time.Now().UTC().Add(cfg.accountDeletionGrace), Valid: true},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't close account", err)
+		return
+	}
+	if err := cfg.revokeAllSessions(r.Context(), userID); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't sign out closed account", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, response{
+		DeleteAfter: user.DeleteAfter.Time,
+	})
+}
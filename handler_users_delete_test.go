@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestDeleteAccount_TombstonesRepliedToChirps(t *testing.T) {
+	s := newTestServer(t)
+	closing := s.signup(t, "gus@example.com")
+	other := s.signup(t, "lalo@example.com")
+
+	replied := s.postChirp(t, closing, map[string]any{"body": "anyone there?"})
+	reply := s.postChirp(t, other, map[string]any{"body": "here", "in_reply_to_id": replied.ID})
+	quoted := s.postChirp(t, closing, map[string]any{"body": "quote me"})
+	s.postChirp(t, other, map[string]any{"body": "quoting", "quote_of_id": quoted.ID})
+	// A chirp only its own author replied to has nothing to keep it.
+	alone := s.postChirp(t, closing, map[string]any{"body": "talking to myself"})
+	s.postChirp(t, closing, map[string]any{"body": "still", "in_reply_to_id": alone.ID})
+	parent := s.postChirp(t, other, map[string]any{"body": "hello"})
+	s.postChirp(t, closing, map[string]any{"body": "hi", "in_reply_to_id": parent.ID})
+
+	decode(t, s.do(t, "DELETE", "/api/users", closing.Token, map[string]string{
+		"password": testPassword,
+	}), http.StatusNoContent, nil)
+
+	var thread struct {
+		Chirp   Chirp         `json:"chirp"`
+		Replies []ThreadReply `json:"replies"`
+	}
+	decode(t, s.do(t, "GET", "/api/chirps/"+replied.ID.String()+"/thread", "", nil), http.StatusOK, &thread)
+	if !thread.Chirp.Deleted || thread.Chirp.Body != "" || thread.Chirp.UserID != nil {
+		t.Errorf("Expected the replied-to chirp to be an authorless tombstone, got %+v", thread.Chirp)
+	}
+	if len(thread.Replies) != 1 || thread.Replies[0].ID != reply.ID {
+		t.Errorf("Expected the reply to stay in the thread, got %+v", thread.Replies)
+	}
+	if thread.Chirp.ReplyCount != 1 {
+		t.Errorf("Expected reply_count 1, got %d", thread.Chirp.ReplyCount)
+	}
+
+	var tombstone bool
+	err := s.conn.QueryRow("SELECT deleted_at IS NOT NULL FROM chirps WHERE id = $1", quoted.ID).Scan(&tombstone)
+	if err != nil || !tombstone {
+		t.Errorf("Expected the quoted chirp to be a tombstone (err %v)", err)
+	}
+	if rec := s.do(t, "GET", "/api/chirps/"+alone.ID.String()+"/thread", "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected a chirp with only its author's replies to be deleted, got %d: %s", rec.Code, rec.Body)
+	}
+
+	var got Chirp
+	decode(t, s.do(t, "GET", "/api/chirps/"+parent.ID.String(), "", nil), http.StatusOK, &got)
+	if got.ReplyCount != 0 {
+		t.Errorf("Expected the deleted reply to be uncounted, got reply_count %d", got.ReplyCount)
+	}
+
+	var wrong int
+	err = s.conn.QueryRow(`SELECT COUNT(*) FROM chirps
+		WHERE reply_count <> (SELECT COUNT(*) FROM chirps replies WHERE replies.in_reply_to_id = chirps.id)`).Scan(&wrong)
+	if err != nil {
+		t.Fatalf("Couldn't check reply counts: %v", err)
+	}
+	if wrong != 0 {
+		t.Errorf("Expected every reply_count to match, %d don't", wrong)
+	}
+}
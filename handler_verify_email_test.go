@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/mvusic07/Chirpy/internal/auth"
+	"github.com/mvusic07/Chirpy/internal/database"
+)
+
+// verificationToken issues a fresh verification link for the user and email
+// and returns its token. The link sent at signup is mailed in the
+// background, so tests take a new one rather than wait for it.
+func (s *testServer) verificationToken(t *testing.T, user User, email string) string {
+	t.Helper()
+	msg, err := s.newEmailVerification(t.Context(), s.db, user.ID, email)
+	if err != nil {
+		t.Fatalf("newEmailVerification failed: %v", err)
+	}
+	return linkToken(t, msg.Body)
+}
+
+func TestVerifyEmail(t *testing.T) {
+	s := newTestServer(t)
+	var user User
+	decode(t, s.do(t, "POST", "/api/users", "", map[string]string{
+		"email":    "jimmy@example.com",
+		"password": testPassword,
+	}), http.StatusCreated, &user)
+	if user.EmailVerified {
+		t.Fatal("Expected a new account's email to be unverified")
+	}
+
+	stale := s.verificationToken(t, user, "jimmy@example.com")
+	token := s.verificationToken(t, user, "jimmy@example.com")
+	if rec := s.do(t, "GET", "/api/verify-email?token="+url.QueryEscape(stale), "", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected a replaced link to be rejected, got %d: %s", rec.Code, rec.Body)
+	}
+
+	var verified User
+	decode(t, s.do(t, "GET", "/api/verify-email?token="+url.QueryEscape(token), "", nil), http.StatusOK, &verified)
+	if !verified.EmailVerified || verified.Email != "jimmy@example.com" {
+		t.Errorf("Expected jimmy@example.com to be verified, got %+v", verified)
+	}
+
+	if rec := s.do(t, "GET", "/api/verify-email?token="+url.QueryEscape(token), "", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected a used link to be rejected, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestVerifyEmail_ChangesEmail(t *testing.T) {
+	s := newTestServer(t)
+	session := s.signup(t, "mesa@example.com")
+
+	token := s.verificationToken(t, session.User, "mesa.verde@example.com")
+	var verified User
+	decode(t, s.do(t, "GET", "/api/verify-email?token="+url.QueryEscape(token), "", nil), http.StatusOK, &verified)
+	if verified.Email != "mesa.verde@example.com" || !verified.EmailVerified {
+		t.Errorf("Expected the new email to replace the old one, got %+v", verified)
+	}
+}
+
+func TestVerifyEmail_Expired(t *testing.T) {
+	s := newTestServer(t)
+	var user User
+	decode(t, s.do(t, "POST", "/api/users", "", map[string]string{
+		"email":    "ernesto@example.com",
+		"password": testPassword,
+	}), http.StatusCreated, &user)
+
+	token := auth.MakeToken()
+	_, err := s.db.CreateEmailVerificationToken(t.Context(), database.CreateEmailVerificationTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    user.ID,
+		Email:     user.Email,
+		ExpiresAt: time.Now().UTC().Add(-time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("CreateEmailVerificationToken failed: %v", err)
+	}
+
+	if rec := s.do(t, "GET", "/api/verify-email?token="+url.QueryEscape(token), "", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected an expired link to be rejected, got %d: %s", rec.Code, rec.Body)
+	}
+	got, err := s.db.GetUserByID(t.Context(), user.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID failed: %v", err)
+	}
+	if got.EmailVerifiedAt.Valid {
+		t.Error("Expected the email to stay unverified")
+	}
+}
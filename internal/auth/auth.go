@@ -2,6 +2,7 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"net/http"
@@ -10,33 +11,48 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
-func HashPassword(password string) (string, error) {
-	pass, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return "", errors.New("error while genertating from password")
-	}
-	return string(pass), nil
-
+// Claims are the claims carried by Chirpy access tokens. SessionID ties the
+// token to the login session (refresh token family) that issued it, and Role
+// is the user's role when the token was issued. IssuedAtMicros is iat to the
+// microsecond: iat is whole seconds, too coarse to tell a token issued just
+// after a revocation from one issued just before it.
+type Claims struct {
+	jwt.RegisteredClaims
+	SessionID      string `json:"sid,omitempty"`
+	Role           string `json:"role,omitempty"`
+	IssuedAtMicros int64  `json:"iat_us,omitempty"`
 }
 
-func CheckPasswordHash(password, hash string) error {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	if err != nil {
-		return errors.New("comapring not passed")
+func newClaims(userID, sessionID uuid.UUID, expiresIn time.Duration) Claims {
+	now := time.Now()
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "chirpy",
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiresIn)),
+			Subject:   userID.String(),
+			ID:        uuid.NewString(),
+		},
+		IssuedAtMicros: now.UnixMicro(),
+	}
+	if sessionID != uuid.Nil {
+		claims.SessionID = sessionID.String()
 	}
-	return nil
+	return claims
 }
 
+// MakeJWT signs an HS256 token with a shared secret. The server itself signs
+// with a KeySet and only accepts HS256 tokens through KeySet.AllowLegacySecret.
 func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		Issuer:    "chirpy",
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
-		Subject:   userID.String(),
-	})
+	return MakeSessionJWT(userID, uuid.Nil, tokenSecret, expiresIn)
+}
+
+// MakeSessionJWT is MakeJWT with a sid claim naming the session the token was
+// issued for. A nil sessionID leaves the claim out.
+func MakeSessionJWT(userID, sessionID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, newClaims(userID, sessionID, expiresIn))
 	signedToken, err := token.SignedString([]byte(tokenSecret))
 	if err != nil {
 		return "", errors.New("error while creating a signed JWT")
@@ -46,7 +62,16 @@ func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (str
 }
 
 func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	claims := &jwt.RegisteredClaims{}
+	claims, err := ParseJWT(tokenString, tokenSecret)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return claims.UserID()
+}
+
+// ParseJWT verifies tokenString and returns all of its claims.
+func ParseJWT(tokenString, tokenSecret string) (*Claims, error) {
+	claims := &Claims{}
 
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 		// Verify the signing method
@@ -57,14 +82,28 @@ func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
 	})
 
 	if err != nil {
-		return uuid.Nil, err
+		return nil, err
 	}
 
 	if !token.Valid {
-		return uuid.Nil, errors.New("invalid token")
+		return nil, errors.New("invalid token")
+	}
+
+	return claims, nil
+}
+
+// TokenID parses the jti claim, returning uuid.Nil if it is absent.
+func (c *Claims) TokenID() uuid.UUID {
+	tokenID, err := uuid.Parse(c.ID)
+	if err != nil {
+		return uuid.Nil
 	}
+	return tokenID
+}
 
-	userIDstring := claims.Subject
+// UserID parses the subject claim.
+func (c *Claims) UserID() (uuid.UUID, error) {
+	userIDstring := c.Subject
 	if userIDstring == "" {
 		return uuid.Nil, errors.New("subject claim is empty")
 	}
@@ -76,17 +115,77 @@ func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
 	return userID, nil
 }
 
+// SessionUUID parses the sid claim, returning uuid.Nil if it is absent.
+func (c *Claims) SessionUUID() uuid.UUID {
+	sessionID, err := uuid.Parse(c.SessionID)
+	if err != nil {
+		return uuid.Nil
+	}
+	return sessionID
+}
+
+// GetBearerToken extracts the token from an "Authorization: Bearer <token>"
+// header. The scheme name is case-insensitive (RFC 7235).
 func GetBearerToken(headers http.Header) (string, error) {
-	bearerToken := headers.Get("Authorization")
-	if bearerToken == "" {
+	authorization := headers.Get("Authorization")
+	scheme, token, ok := strings.Cut(authorization, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", errors.New("no bearer token found")
+	}
+	token = strings.TrimSpace(token)
+	if token == "" {
 		return "", errors.New("no bearer token found")
 	}
-	token := strings.TrimSpace(strings.TrimPrefix(bearerToken, "Bearer"))
 	return token, nil
 }
 
+// GetAPIKey extracts the key from an "Authorization: ApiKey <key>" header.
+func GetAPIKey(headers http.Header) (string, error) {
+	authorization := headers.Get("Authorization")
+	key, ok := strings.CutPrefix(authorization, "ApiKey ")
+	if !ok || strings.TrimSpace(key) == "" {
+		return "", errors.New("no API key found")
+	}
+	return strings.TrimSpace(key), nil
+}
+
 func MakeRefreshToken() string {
+	return MakeToken()
+}
+
+// HashRefreshToken returns the digest stored in place of a refresh token, so
+// that reading the database is not enough to hijack a session.
+func HashRefreshToken(token string) string {
+	return HashToken(token)
+}
+
+// MakeToken returns 256 random bits, hex encoded, for opaque bearer secrets
+// such as refresh tokens and emailed links.
+func MakeToken() string {
 	token := make([]byte, 32)
 	rand.Read(token)
 	return hex.EncodeToString(token)
 }
+
+// HashToken returns the SHA-256 digest of a token made by MakeToken. The
+// tokens are random enough that no salt or stretching is needed.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// personalAccessTokenPrefix marks personal access tokens so they can be told
+// apart from JWTs in an Authorization header, and found by secret scanners.
+const personalAccessTokenPrefix = "chirpy_pat_"
+
+// MakePersonalAccessToken returns a new personal access token. Store only
+// HashToken(token).
+func MakePersonalAccessToken() string {
+	return personalAccessTokenPrefix + MakeToken()
+}
+
+// IsPersonalAccessToken reports whether token looks like one made by
+// MakePersonalAccessToken.
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, personalAccessTokenPrefix)
+}
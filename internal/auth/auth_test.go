@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"net/http"
 	"strings"
 	"testing"
 	"time"
@@ -329,3 +330,137 @@ func TestJWT_DifferentSecrets(t *testing.T) {
 		}
 	}
 }
+
+func TestHashRefreshToken(t *testing.T) {
+	token := MakeRefreshToken()
+
+	hash := HashRefreshToken(token)
+	if hash == token {
+		t.Error("Hash should not equal original token")
+	}
+	if len(hash) != 64 {
+		t.Errorf("Expected 64 hex characters, got %d", len(hash))
+	}
+	if HashRefreshToken(token) != hash {
+		t.Error("Expected the same token to hash to the same digest")
+	}
+	if HashRefreshToken(MakeRefreshToken()) == hash {
+		t.Error("Expected different tokens to hash to different digests")
+	}
+}
+
+func TestMakeSessionJWT(t *testing.T) {
+	userID := uuid.New()
+	sessionID := uuid.New()
+	tokenSecret := "test-secret"
+
+	tokenString, err := MakeSessionJWT(userID, sessionID, tokenSecret, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeSessionJWT failed: %v", err)
+	}
+
+	claims, err := ParseJWT(tokenString, tokenSecret)
+	if err != nil {
+		t.Fatalf("ParseJWT failed: %v", err)
+	}
+	if got := claims.SessionUUID(); got != sessionID {
+		t.Errorf("Expected session ID %v, got %v", sessionID, got)
+	}
+	if got, err := claims.UserID(); err != nil || got != userID {
+		t.Errorf("Expected user ID %v, got %v (%v)", userID, got, err)
+	}
+
+	// Tokens made without a session have no sid claim
+	tokenString, err = MakeJWT(userID, tokenSecret, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+	claims, err = ParseJWT(tokenString, tokenSecret)
+	if err != nil {
+		t.Fatalf("ParseJWT failed: %v", err)
+	}
+	if claims.SessionID != "" {
+		t.Errorf("Expected no sid claim, got %q", claims.SessionID)
+	}
+}
+
+func TestGetAPIKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		want    string
+		wantErr bool
+	}{
+		{name: "valid", header: "ApiKey abc123", want: "abc123"},
+		{name: "missing", header: "", wantErr: true},
+		{name: "wrong scheme", header: "Bearer abc123", wantErr: true},
+		{name: "no key", header: "ApiKey ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := http.Header{}
+			if tt.header != "" {
+				headers.Set("Authorization", tt.header)
+			}
+			got, err := GetAPIKey(headers)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMakePersonalAccessToken(t *testing.T) {
+	token := MakePersonalAccessToken()
+	if !IsPersonalAccessToken(token) {
+		t.Errorf("Expected %q to be recognised as a personal access token", token)
+	}
+	if token == MakePersonalAccessToken() {
+		t.Error("Expected tokens to be unique")
+	}
+
+	jwt, err := MakeJWT(uuid.New(), "test-secret", time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+	if IsPersonalAccessToken(jwt) {
+		t.Error("Expected a JWT not to be recognised as a personal access token")
+	}
+}
+
+func TestGetBearerToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		want    string
+		wantErr bool
+	}{
+		{name: "valid", header: "Bearer abc.def.ghi", want: "abc.def.ghi"},
+		{name: "lowercase scheme", header: "bearer abc", want: "abc"},
+		{name: "missing", header: "", wantErr: true},
+		{name: "no scheme", header: "abc.def.ghi", wantErr: true},
+		{name: "scheme without space", header: "Bearerabc", wantErr: true},
+		{name: "other scheme", header: "ApiKey abc", wantErr: true},
+		{name: "no token", header: "Bearer ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := http.Header{}
+			if tt.header != "" {
+				headers.Set("Authorization", tt.header)
+			}
+			got, err := GetBearerToken(headers)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
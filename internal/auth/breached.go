@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// BreachedPasswords answers k-anonymity range queries in the style of the
+// Have I Been Pwned API: given the first five hex digits of a password's
+// SHA-1, it returns the remaining 35 digits of every breached password
+// sharing that prefix. The full hash never has to leave the caller.
+type BreachedPasswords interface {
+	Range(prefix string) ([]string, error)
+}
+
+const breachedPrefixLen = 5
+
+// IsBreached reports whether password appears in corpus.
+func IsBreached(corpus BreachedPasswords, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	digest := strings.ToUpper(hex.EncodeToString(sum[:]))
+	suffixes, err := corpus.Range(digest[:breachedPrefixLen])
+	if err != nil {
+		return false, err
+	}
+	for _, suffix := range suffixes {
+		if suffix == digest[breachedPrefixLen:] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// BreachedPasswordFile serves range queries from a local file of uppercase
+// SHA-1 hashes sorted in ascending order, one per line, optionally followed
+// by ":count" as in the HIBP downloads. Only an index of where each prefix
+// starts is kept in memory; ranges are read from disk on demand.
+type BreachedPasswordFile struct {
+	file *os.File
+	// offsets[p] is the position of the first line whose prefix is >= p.
+	offsets []int64
+}
+
+// OpenBreachedPasswordFile indexes the corpus at path.
+func OpenBreachedPasswordFile(path string) (*BreachedPasswordFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	offsets, err := indexBreachedPasswords(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &BreachedPasswordFile{file: f, offsets: offsets}, nil
+}
+
+func indexBreachedPasswords(r io.Reader) ([]int64, error) {
+	offsets := make([]int64, 1<<(4*breachedPrefixLen)+1)
+	next := 0
+	var pos int64
+	var last string
+	reader := bufio.NewReader(r)
+	for lineNo := 1; ; lineNo++ {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if line == "" {
+			break
+		}
+		if strings.TrimSpace(line) == "" {
+			// Blank lines, such as a trailing one, hold no hash.
+			pos += int64(len(line))
+			if err == io.EOF {
+				break
+			}
+			continue
+		}
+		hash, _, _ := strings.Cut(strings.TrimSpace(line), ":")
+		hash = strings.ToUpper(hash)
+		if len(hash) != 2*sha1.Size {
+			return nil, fmt.Errorf("line %d: not a SHA-1 hash", lineNo)
+		}
+		if hash < last {
+			return nil, fmt.Errorf("line %d: hashes are not sorted", lineNo)
+		}
+		last = hash
+		prefix, perr := strconv.ParseUint(hash[:breachedPrefixLen], 16, 32)
+		if perr != nil {
+			return nil, fmt.Errorf("line %d: not a SHA-1 hash", lineNo)
+		}
+		for ; next <= int(prefix); next++ {
+			offsets[next] = pos
+		}
+		pos += int64(len(line))
+		if err == io.EOF {
+			break
+		}
+	}
+	for ; next < len(offsets); next++ {
+		offsets[next] = pos
+	}
+	return offsets, nil
+}
+
+func (b *BreachedPasswordFile) Range(prefix string) ([]string, error) {
+	p, err := strconv.ParseUint(prefix, 16, 32)
+	if err != nil || len(prefix) != breachedPrefixLen {
+		return nil, errors.New("invalid hash prefix")
+	}
+	start, end := b.offsets[p], b.offsets[p+1]
+	buf := make([]byte, end-start)
+	if _, err := b.file.ReadAt(buf, start); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	var suffixes []string
+	for _, line := range strings.Split(string(buf), "\n") {
+		hash, _, _ := strings.Cut(strings.TrimSpace(line), ":")
+		if hash != "" {
+			suffixes = append(suffixes, strings.ToUpper(hash[breachedPrefixLen:]))
+		}
+	}
+	return suffixes, nil
+}
+
+func (b *BreachedPasswordFile) Close() error {
+	return b.file.Close()
+}
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/text/unicode/norm"
+)
+
+var ErrInvalidEmail = errors.New("invalid email address")
+
+// emailDomain converts internationalized domains to their ASCII (punycode)
+// form, so that a domain typed in Unicode and one typed as punycode are the
+// same address.
+var emailDomain = idna.New(
+	idna.MapForLookup(),
+	idna.BidiRule(),
+	idna.Transitional(false),
+)
+
+// NormalizeEmail validates a bare email address and returns the form it is
+// stored and looked up by: trimmed, NFC-normalized, lowercased, with the
+// domain in ASCII. Addresses differing only in case are treated as the same
+// mailbox; almost every provider does, and the alternative is two accounts
+// for one person. Display names, quoted local parts and domains without a
+// dot are rejected.
+func NormalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	at := strings.LastIndexByte(email, '@')
+	if at <= 0 || at == len(email)-1 {
+		return "", ErrInvalidEmail
+	}
+	local, domain := email[:at], email[at+1:]
+
+	local = strings.ToLower(norm.NFC.String(local))
+	if len(local) > 64 || strings.HasPrefix(local, `"`) {
+		return "", ErrInvalidEmail
+	}
+	domain, err := emailDomain.ToASCII(domain)
+	if err != nil || !strings.Contains(domain, ".") {
+		return "", ErrInvalidEmail
+	}
+	domain = strings.ToLower(domain)
+
+	normalized := local + "@" + domain
+	if len(normalized) > 254 {
+		return "", ErrInvalidEmail
+	}
+	// net/mail checks the local part's syntax; it must parse as exactly the
+	// bare address, with nothing around it.
+	addr, err := mail.ParseAddress(normalized)
+	if err != nil || addr.Name != "" || addr.Address != normalized {
+		return "", ErrInvalidEmail
+	}
+	return normalized, nil
+}
@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: "walt@breakingbad.com", want: "walt@breakingbad.com"},
+		{input: "  Walt@BreakingBad.COM ", want: "walt@breakingbad.com"},
+		{input: "jesse+chirpy@breakingbad.com", want: "jesse+chirpy@breakingbad.com"},
+		{input: "saul@bücher.example", want: "saul@xn--bcher-kva.example"},
+		{input: "saul@BÜCHER.example", want: "saul@xn--bcher-kva.example"},
+		{input: "saul@xn--bcher-kva.example", want: "saul@xn--bcher-kva.example"},
+		{input: "JOSÉ@example.com", want: "josé@example.com"},
+		// "é" precomposed and as "e" plus a combining accent.
+		{input: "jos\u00e9@example.com", want: "jos\u00e9@example.com"},
+		{input: "jose\u0301@example.com", want: "jos\u00e9@example.com"},
+		{input: "", wantErr: true},
+		{input: "walt", wantErr: true},
+		{input: "@breakingbad.com", wantErr: true},
+		{input: "walt@", wantErr: true},
+		{input: "walt@localhost", wantErr: true},
+		{input: "walt white@breakingbad.com", wantErr: true},
+		{input: "Walt <walt@breakingbad.com>", wantErr: true},
+		{input: `"walt"@breakingbad.com`, wantErr: true},
+		{input: "walt@@breakingbad.com", wantErr: true},
+		{input: "walt@breaking_bad..com", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := NormalizeEmail(tt.input)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidEmail) {
+					t.Errorf("Expected ErrInvalidEmail, got %q, %v", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NormalizeEmail failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,477 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// ErrTokenRevoked is returned for access tokens that were revoked before they
+// expired.
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// createdHeader is the PEM header recording when a generated key was created.
+// Keys dropped into the directory by hand fall back to the file's mod time.
+const createdHeader = "Created"
+
+// activatesHeader is the PEM header recording when a generated key may start
+// signing. Keys without one sign as soon as they are created.
+const activatesHeader = "Activates"
+
+type signingKey struct {
+	id          string
+	method      jwt.SigningMethod
+	private     crypto.Signer
+	createdAt   time.Time
+	activatesAt time.Time
+}
+
+// KeySet holds the asymmetric keys used to sign and verify access tokens.
+// Every key in the directory is accepted for verification; the newest one
+// that has activated signs. Each key is stored as <kid>.pem holding a PKCS#8
+// (or PKCS#1 RSA) private key.
+type KeySet struct {
+	dir          string
+	legacySecret string
+	revocations  RevocationChecker
+
+	mu   sync.RWMutex
+	keys map[string]*signingKey
+}
+
+// LoadKeySet reads every key in dir, generating a first Ed25519 key if the
+// directory is empty.
+func LoadKeySet(dir string) (*KeySet, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	ks := &KeySet{dir: dir}
+	if err := ks.Reload(); err != nil {
+		return nil, err
+	}
+	if len(ks.keys) == 0 {
+		if err := ks.Rotate(0); err != nil {
+			return nil, err
+		}
+	}
+	return ks, nil
+}
+
+// AllowLegacySecret makes the key set also accept HS256 tokens signed with
+// secret, so tokens issued before the switch to asymmetric keys stay valid
+// until they expire. It is never used for signing.
+func (ks *KeySet) AllowLegacySecret(secret string) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.legacySecret = secret
+}
+
+// RevocationChecker reports whether an otherwise valid access token has been
+// revoked before its expiry.
+type RevocationChecker interface {
+	IsRevoked(claims *Claims) bool
+}
+
+// SetRevocationChecker makes ParseJWT and ValidateJWT reject tokens that rc
+// reports as revoked.
+func (ks *KeySet) SetRevocationChecker(rc RevocationChecker) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.revocations = rc
+}
+
+// Reload re-reads the key directory, picking up keys added or removed by
+// another instance or by an operator.
+func (ks *KeySet) Reload() error {
+	paths, err := filepath.Glob(filepath.Join(ks.dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]*signingKey, len(paths))
+	for _, path := range paths {
+		key, err := readKey(path)
+		if err != nil {
+			return fmt.Errorf("loading %s: %w", path, err)
+		}
+		keys[key.id] = key
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.keys = keys
+	return nil
+}
+
+// signer returns the key that signs at now: the one activated most recently,
+// ties going to the higher kid so every instance picks the same key.
+// ks.mu must be held.
+func (ks *KeySet) signer(now time.Time) *signingKey {
+	var current *signingKey
+	for _, key := range ks.keys {
+		if key.activatesAt.After(now) {
+			continue
+		}
+		if current == nil || key.activatesAt.After(current.activatesAt) ||
+			key.activatesAt.Equal(current.activatesAt) && key.id > current.id {
+			current = key
+		}
+	}
+	return current
+}
+
+// Rotate generates a new Ed25519 key and writes it to the directory, where it
+// is published for verification straight away but only starts signing after
+// activateIn. Instances sharing the directory must reload it within that
+// time, or they would reject tokens signed with the new key. Older keys
+// remain valid for verification until pruned.
+func (ks *KeySet) Rotate(activateIn time.Duration) error {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	activatesAt := now.Add(activateIn)
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	id := now.Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix)
+
+	data := pem.EncodeToMemory(&pem.Block{
+		Type: "PRIVATE KEY",
+		Headers: map[string]string{
+			createdHeader:   now.Format(time.RFC3339Nano),
+			activatesHeader: activatesAt.Format(time.RFC3339Nano),
+		},
+		Bytes: der,
+	})
+	if err := os.WriteFile(filepath.Join(ks.dir, id+".pem"), data, 0o600); err != nil {
+		return err
+	}
+
+	key := &signingKey{
+		id:          id,
+		method:      jwt.SigningMethodEdDSA,
+		private:     private,
+		createdAt:   now,
+		activatesAt: activatesAt,
+	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if ks.keys == nil {
+		ks.keys = map[string]*signingKey{}
+	}
+	ks.keys[id] = key
+	return nil
+}
+
+// Prune deletes keys that stopped signing more than retain ago. retain should
+// be at least the lifetime of the longest-lived token signed with them.
+func (ks *KeySet) Prune(retain time.Duration) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	keys := make([]*signingKey, 0, len(ks.keys))
+	for _, key := range ks.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].activatesAt.Equal(keys[j].activatesAt) {
+			return keys[i].id < keys[j].id
+		}
+		return keys[i].activatesAt.Before(keys[j].activatesAt)
+	})
+
+	now := time.Now()
+	current := ks.signer(now)
+	cutoff := now.Add(-retain)
+	for i := 0; i < len(keys)-1; i++ {
+		// A key stops signing when the next one activates.
+		if keys[i] == current || keys[i+1].activatesAt.After(cutoff) {
+			continue
+		}
+		err := os.Remove(filepath.Join(ks.dir, keys[i].id+".pem"))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		delete(ks.keys, keys[i].id)
+	}
+	return nil
+}
+
+// Maintain reloads the key directory every interval until ctx is done. If
+// rotateEvery is non-zero a new key is generated once the newest one is that
+// old and superseded keys are pruned after retain. A new key only signs two
+// intervals after it is written, by which time every instance sharing the
+// directory has reloaded it, so it doesn't matter which of them rotates.
+func (ks *KeySet) Maintain(ctx context.Context, interval, rotateEvery, retain time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := ks.Reload(); err != nil {
+			log.Printf("Couldn't reload signing keys: %s", err)
+			continue
+		}
+		if rotateEvery == 0 {
+			continue
+		}
+		ks.mu.RLock()
+		due := true
+		for _, key := range ks.keys {
+			if time.Since(key.createdAt) < rotateEvery {
+				due = false
+				break
+			}
+		}
+		ks.mu.RUnlock()
+		if due {
+			if err := ks.Rotate(2 * interval); err != nil {
+				log.Printf("Couldn't rotate signing key: %s", err)
+				continue
+			}
+			log.Printf("Rotated JWT signing key")
+		}
+		if err := ks.Prune(retain); err != nil {
+			log.Printf("Couldn't prune signing keys: %s", err)
+		}
+	}
+}
+
+// MakeJWT signs an access token for userID with the current key. A nil
+// sessionID leaves the sid claim out.
+func (ks *KeySet) MakeJWT(userID, sessionID uuid.UUID, expiresIn time.Duration) (string, error) {
+	return ks.Sign(newClaims(userID, sessionID, expiresIn))
+}
+
+// MakeRoleJWT is MakeJWT with a role claim. The role is a snapshot taken at
+// issue time; revoke the user's tokens when it changes.
+func (ks *KeySet) MakeRoleJWT(userID, sessionID uuid.UUID, role string, expiresIn time.Duration) (string, error) {
+	claims := newClaims(userID, sessionID, expiresIn)
+	claims.Role = role
+	return ks.Sign(claims)
+}
+
+// Sign signs arbitrary claims with the current key, setting the kid header.
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	ks.mu.RLock()
+	key := ks.signer(time.Now())
+	ks.mu.RUnlock()
+	if key == nil {
+		return "", errors.New("no signing key available")
+	}
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.id
+	signedToken, err := token.SignedString(key.private)
+	if err != nil {
+		return "", errors.New("error while creating a signed JWT")
+	}
+	return signedToken, nil
+}
+
+// ValidateJWT verifies tokenString and returns the user ID in its subject.
+func (ks *KeySet) ValidateJWT(tokenString string) (uuid.UUID, error) {
+	claims, err := ks.ParseJWT(tokenString)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return claims.UserID()
+}
+
+// ParseJWT verifies an access token, checks that it has not been revoked and
+// returns its claims.
+func (ks *KeySet) ParseJWT(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	if err := ks.Parse(tokenString, claims); err != nil {
+		return nil, err
+	}
+	// Access tokens have no audience; anything with one was issued for
+	// another purpose, such as an MFA challenge.
+	if len(claims.Audience) != 0 {
+		return nil, errors.New("not an access token")
+	}
+
+	ks.mu.RLock()
+	revocations := ks.revocations
+	ks.mu.RUnlock()
+	if revocations != nil && revocations.IsRevoked(claims) {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
+
+// Parse verifies tokenString against the key named by its kid header and
+// decodes it into claims.
+func (ks *KeySet) Parse(tokenString string, claims jwt.Claims, opts ...jwt.ParserOption) error {
+	ks.mu.RLock()
+	legacySecret := ks.legacySecret
+	ks.mu.RUnlock()
+
+	methods := []string{jwt.SigningMethodEdDSA.Alg(), jwt.SigningMethodRS256.Alg()}
+	if legacySecret != "" {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+			return []byte(legacySecret), nil
+		}
+		kid, _ := token.Header["kid"].(string)
+		ks.mu.RLock()
+		key, ok := ks.keys[kid]
+		ks.mu.RUnlock()
+		if !ok {
+			return nil, errors.New("unknown signing key")
+		}
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, errors.New("unexpected signing method")
+		}
+		return key.private.Public(), nil
+	}, append(opts, jwt.WithValidMethods(methods))...)
+	if err != nil {
+		return err
+	}
+	if !token.Valid {
+		return errors.New("invalid token")
+	}
+	return nil
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public half of every verification key, newest first.
+func (ks *KeySet) JWKS() JWKS {
+	ks.mu.RLock()
+	keys := make([]*signingKey, 0, len(ks.keys))
+	for _, key := range ks.keys {
+		keys = append(keys, key)
+	}
+	ks.mu.RUnlock()
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].createdAt.After(keys[j].createdAt)
+	})
+
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range keys {
+		jwk := JWK{
+			Kid: key.id,
+			Use: "sig",
+			Alg: key.method.Alg(),
+		}
+		switch public := key.private.Public().(type) {
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+func readKey(path string) (*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var private any
+	switch block.Type {
+	case "PRIVATE KEY":
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &signingKey{id: strings.TrimSuffix(filepath.Base(path), ".pem")}
+	switch private := private.(type) {
+	case ed25519.PrivateKey:
+		key.method = jwt.SigningMethodEdDSA
+		key.private = private
+	case *rsa.PrivateKey:
+		key.method = jwt.SigningMethodRS256
+		key.private = private
+	default:
+		return nil, errors.New("only Ed25519 and RSA keys are supported")
+	}
+
+	if created, ok := block.Headers[createdHeader]; ok {
+		key.createdAt, err = time.Parse(time.RFC3339Nano, created)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s header: %w", createdHeader, err)
+		}
+	} else {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		key.createdAt = info.ModTime()
+	}
+	key.activatesAt = key.createdAt
+	if activates, ok := block.Headers[activatesHeader]; ok {
+		key.activatesAt, err = time.Parse(time.RFC3339Nano, activates)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s header: %w", activatesHeader, err)
+		}
+	}
+	return key, nil
+}
@@ -0,0 +1,397 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func TestLoadKeySet_GeneratesKey(t *testing.T) {
+	dir := t.TempDir()
+
+	ks, err := LoadKeySet(dir)
+	if err != nil {
+		t.Fatalf("LoadKeySet failed: %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 generated key file, got %d", len(files))
+	}
+	if len(ks.JWKS().Keys) != 1 {
+		t.Errorf("Expected 1 key in JWKS, got %d", len(ks.JWKS().Keys))
+	}
+
+	// Loading again reuses the key on disk
+	again, err := LoadKeySet(dir)
+	if err != nil {
+		t.Fatalf("LoadKeySet failed on reload: %v", err)
+	}
+	if again.JWKS().Keys[0].Kid != ks.JWKS().Keys[0].Kid {
+		t.Error("Expected the existing key to be loaded")
+	}
+}
+
+func TestKeySet_RoundTrip(t *testing.T) {
+	ks, err := LoadKeySet(t.TempDir())
+	if err != nil {
+		t.Fatalf("LoadKeySet failed: %v", err)
+	}
+	userID := uuid.New()
+	sessionID := uuid.New()
+
+	tokenString, err := ks.MakeJWT(userID, sessionID, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+
+	claims, err := ks.ParseJWT(tokenString)
+	if err != nil {
+		t.Fatalf("ParseJWT failed: %v", err)
+	}
+	if got, _ := claims.UserID(); got != userID {
+		t.Errorf("Expected user ID %v, got %v", userID, got)
+	}
+	if got := claims.SessionUUID(); got != sessionID {
+		t.Errorf("Expected session ID %v, got %v", sessionID, got)
+	}
+
+	token, _, err := jwt.NewParser().ParseUnverified(tokenString, &Claims{})
+	if err != nil {
+		t.Fatalf("ParseUnverified failed: %v", err)
+	}
+	if token.Header["alg"] != "EdDSA" {
+		t.Errorf("Expected EdDSA, got %v", token.Header["alg"])
+	}
+	if token.Header["kid"] != ks.JWKS().Keys[0].Kid {
+		t.Errorf("Expected kid %v, got %v", ks.JWKS().Keys[0].Kid, token.Header["kid"])
+	}
+}
+
+func TestKeySet_RoleClaim(t *testing.T) {
+	ks, err := LoadKeySet(t.TempDir())
+	if err != nil {
+		t.Fatalf("LoadKeySet failed: %v", err)
+	}
+
+	tokenString, err := ks.MakeRoleJWT(uuid.New(), uuid.New(), "admin", time.Hour)
+	if err != nil {
+		t.Fatalf("MakeRoleJWT failed: %v", err)
+	}
+	claims, err := ks.ParseJWT(tokenString)
+	if err != nil {
+		t.Fatalf("ParseJWT failed: %v", err)
+	}
+	if claims.Role != "admin" {
+		t.Errorf("Expected role admin, got %q", claims.Role)
+	}
+
+	tokenString, err = ks.MakeJWT(uuid.New(), uuid.New(), time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+	claims, err = ks.ParseJWT(tokenString)
+	if err != nil {
+		t.Fatalf("ParseJWT failed: %v", err)
+	}
+	if claims.Role != "" {
+		t.Errorf("Expected no role, got %q", claims.Role)
+	}
+}
+
+func TestKeySet_Expired(t *testing.T) {
+	ks, err := LoadKeySet(t.TempDir())
+	if err != nil {
+		t.Fatalf("LoadKeySet failed: %v", err)
+	}
+
+	tokenString, err := ks.MakeJWT(uuid.New(), uuid.Nil, -time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+	_, err = ks.ValidateJWT(tokenString)
+	if err == nil || !strings.Contains(err.Error(), "token is expired") {
+		t.Errorf("Expected 'token is expired' error, got: %v", err)
+	}
+}
+
+func TestKeySet_Rotate(t *testing.T) {
+	ks, err := LoadKeySet(t.TempDir())
+	if err != nil {
+		t.Fatalf("LoadKeySet failed: %v", err)
+	}
+	userID := uuid.New()
+
+	oldToken, err := ks.MakeJWT(userID, uuid.Nil, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+	if err := ks.Rotate(0); err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+	newToken, err := ks.MakeJWT(userID, uuid.Nil, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+
+	for _, tokenString := range []string{oldToken, newToken} {
+		if _, err := ks.ValidateJWT(tokenString); err != nil {
+			t.Errorf("ValidateJWT failed after rotation: %v", err)
+		}
+	}
+	if len(ks.JWKS().Keys) != 2 {
+		t.Errorf("Expected 2 keys in JWKS, got %d", len(ks.JWKS().Keys))
+	}
+
+	// The old key was superseded just now, so it survives a long retention...
+	if err := ks.Prune(time.Hour); err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if _, err := ks.ValidateJWT(oldToken); err != nil {
+		t.Errorf("Expected old token to survive prune: %v", err)
+	}
+
+	// ...but not a zero one, while the signing key is always kept
+	if err := ks.Prune(-time.Second); err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if _, err := ks.ValidateJWT(oldToken); err == nil {
+		t.Error("Expected old token to be rejected after its key was pruned")
+	}
+	if _, err := ks.ValidateJWT(newToken); err != nil {
+		t.Errorf("Expected new token to survive prune: %v", err)
+	}
+	if len(ks.JWKS().Keys) != 1 {
+		t.Errorf("Expected 1 key in JWKS, got %d", len(ks.JWKS().Keys))
+	}
+}
+
+func TestKeySet_RotateSharedDirectory(t *testing.T) {
+	dir := t.TempDir()
+	ks1, err := LoadKeySet(dir)
+	if err != nil {
+		t.Fatalf("LoadKeySet failed: %v", err)
+	}
+	ks2, err := LoadKeySet(dir)
+	if err != nil {
+		t.Fatalf("LoadKeySet failed: %v", err)
+	}
+	userID := uuid.New()
+	oldKid := kidOf(t, ks1, userID)
+
+	// A pending key is published but nobody signs with it, so an instance
+	// that hasn't reloaded yet still accepts every token.
+	if err := ks1.Rotate(100 * time.Millisecond); err != nil {
+		t.Fatalf("Rotate failed: %v", err)
+	}
+	tokenString, err := ks1.MakeJWT(userID, uuid.Nil, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+	if _, err := ks2.ValidateJWT(tokenString); err != nil {
+		t.Errorf("Expected a stale key set to accept a token signed after rotation: %v", err)
+	}
+	if kid := kidOf(t, ks1, userID); kid != oldKid {
+		t.Errorf("Expected the old key to sign until the new one activates, got %s", kid)
+	}
+
+	if err := ks2.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if len(ks2.JWKS().Keys) != 2 {
+		t.Errorf("Expected the pending key to be published, got %d keys", len(ks2.JWKS().Keys))
+	}
+
+	// Once it activates both instances sign with it.
+	time.Sleep(150 * time.Millisecond)
+	newKid := kidOf(t, ks1, userID)
+	if newKid == oldKid {
+		t.Fatal("Expected the new key to sign once activated")
+	}
+	if kid := kidOf(t, ks2, userID); kid != newKid {
+		t.Errorf("Expected both key sets to sign with %s, got %s", newKid, kid)
+	}
+	tokenString, err = ks1.MakeJWT(userID, uuid.Nil, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+	if _, err := ks2.ValidateJWT(tokenString); err != nil {
+		t.Errorf("Expected the other key set to accept the new key: %v", err)
+	}
+}
+
+// kidOf signs a token with ks and returns the key ID in its header.
+func kidOf(t *testing.T, ks *KeySet, userID uuid.UUID) string {
+	t.Helper()
+	tokenString, err := ks.MakeJWT(userID, uuid.Nil, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+	token, _, err := jwt.NewParser().ParseUnverified(tokenString, &Claims{})
+	if err != nil {
+		t.Fatalf("ParseUnverified failed: %v", err)
+	}
+	kid, _ := token.Header["kid"].(string)
+	return kid
+}
+
+func TestKeySet_OtherKeySetRejected(t *testing.T) {
+	ks1, err := LoadKeySet(t.TempDir())
+	if err != nil {
+		t.Fatalf("LoadKeySet failed: %v", err)
+	}
+	ks2, err := LoadKeySet(t.TempDir())
+	if err != nil {
+		t.Fatalf("LoadKeySet failed: %v", err)
+	}
+
+	tokenString, err := ks1.MakeJWT(uuid.New(), uuid.Nil, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+	if _, err := ks2.ValidateJWT(tokenString); err == nil {
+		t.Error("Expected token from another key set to be rejected")
+	}
+}
+
+func TestKeySet_RSAKey(t *testing.T) {
+	dir := t.TempDir()
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(private),
+	})
+	if err := os.WriteFile(filepath.Join(dir, "ops-rsa.pem"), data, 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	ks, err := LoadKeySet(dir)
+	if err != nil {
+		t.Fatalf("LoadKeySet failed: %v", err)
+	}
+	tokenString, err := ks.MakeJWT(uuid.New(), uuid.Nil, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+	token, _, _ := jwt.NewParser().ParseUnverified(tokenString, &Claims{})
+	if token.Header["alg"] != "RS256" || token.Header["kid"] != "ops-rsa" {
+		t.Errorf("Expected RS256 with kid ops-rsa, got %v", token.Header)
+	}
+	if _, err := ks.ValidateJWT(tokenString); err != nil {
+		t.Errorf("ValidateJWT failed: %v", err)
+	}
+
+	jwks := ks.JWKS()
+	if len(jwks.Keys) != 1 || jwks.Keys[0].Kty != "RSA" || jwks.Keys[0].E != "AQAB" {
+		t.Errorf("Unexpected JWKS: %+v", jwks)
+	}
+}
+
+func TestKeySet_LegacySecret(t *testing.T) {
+	ks, err := LoadKeySet(t.TempDir())
+	if err != nil {
+		t.Fatalf("LoadKeySet failed: %v", err)
+	}
+	userID := uuid.New()
+	legacyToken, err := MakeJWT(userID, "legacy-secret", time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+
+	if _, err := ks.ValidateJWT(legacyToken); err == nil {
+		t.Error("Expected HS256 token to be rejected without a legacy secret")
+	}
+
+	ks.AllowLegacySecret("legacy-secret")
+	validatedUserID, err := ks.ValidateJWT(legacyToken)
+	if err != nil {
+		t.Fatalf("ValidateJWT failed with legacy secret: %v", err)
+	}
+	if validatedUserID != userID {
+		t.Errorf("Expected user ID %v, got %v", userID, validatedUserID)
+	}
+
+	forged, _ := MakeJWT(userID, "wrong-secret", time.Hour)
+	if _, err := ks.ValidateJWT(forged); err == nil {
+		t.Error("Expected HS256 token with the wrong secret to be rejected")
+	}
+}
+
+type revokeSubject uuid.UUID
+
+func (r revokeSubject) IsRevoked(claims *Claims) bool {
+	return claims.Subject == uuid.UUID(r).String()
+}
+
+func TestKeySet_RevocationChecker(t *testing.T) {
+	ks, err := LoadKeySet(t.TempDir())
+	if err != nil {
+		t.Fatalf("LoadKeySet failed: %v", err)
+	}
+	revoked := uuid.New()
+	ks.SetRevocationChecker(revokeSubject(revoked))
+
+	tokenString, err := ks.MakeJWT(revoked, uuid.Nil, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+	if _, err := ks.ValidateJWT(tokenString); err != ErrTokenRevoked {
+		t.Errorf("Expected ErrTokenRevoked, got: %v", err)
+	}
+
+	tokenString, err = ks.MakeJWT(uuid.New(), uuid.Nil, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+	claims, err := ks.ParseJWT(tokenString)
+	if err != nil {
+		t.Fatalf("ParseJWT failed: %v", err)
+	}
+	if claims.TokenID() == uuid.Nil {
+		t.Error("Expected a jti claim")
+	}
+}
+
+func TestKeySet_MFAChallenge(t *testing.T) {
+	ks, err := LoadKeySet(t.TempDir())
+	if err != nil {
+		t.Fatalf("LoadKeySet failed: %v", err)
+	}
+	userID := uuid.New()
+
+	challenge, err := ks.MakeMFAChallenge(userID, "laptop", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("MakeMFAChallenge failed: %v", err)
+	}
+	claims, err := ks.ParseMFAChallenge(challenge)
+	if err != nil {
+		t.Fatalf("ParseMFAChallenge failed: %v", err)
+	}
+	if got, _ := claims.UserID(); got != userID || claims.SessionName != "laptop" {
+		t.Errorf("Unexpected claims: %+v", claims)
+	}
+
+	// A challenge is not an access token, and the other way round
+	if _, err := ks.ValidateJWT(challenge); err == nil {
+		t.Error("Expected MFA challenge to be rejected as an access token")
+	}
+	accessToken, err := ks.MakeJWT(userID, uuid.Nil, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+	if _, err := ks.ParseMFAChallenge(accessToken); err == nil {
+		t.Error("Expected access token to be rejected as an MFA challenge")
+	}
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const mfaChallengeAudience = "chirpy:mfa"
+
+// MFAChallengeClaims are carried by the short-lived token handed out after a
+// correct password when the account has two-factor authentication enabled.
+// It proves the first factor and nothing else.
+type MFAChallengeClaims struct {
+	jwt.RegisteredClaims
+	SessionName string `json:"session_name,omitempty"`
+}
+
+// MakeMFAChallenge signs a challenge token for userID. sessionName is carried
+// through to the session created once the second factor is checked.
+func (ks *KeySet) MakeMFAChallenge(userID uuid.UUID, sessionName string, expiresIn time.Duration) (string, error) {
+	return ks.Sign(MFAChallengeClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "chirpy",
+			Audience:  jwt.ClaimStrings{mfaChallengeAudience},
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+			Subject:   userID.String(),
+			ID:        uuid.NewString(),
+		},
+		SessionName: sessionName,
+	})
+}
+
+// ParseMFAChallenge verifies a challenge token and returns its claims.
+func (ks *KeySet) ParseMFAChallenge(tokenString string) (*MFAChallengeClaims, error) {
+	claims := &MFAChallengeClaims{}
+	err := ks.Parse(tokenString, claims, jwt.WithAudience(mfaChallengeAudience))
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// UserID parses the subject claim.
+func (c *MFAChallengeClaims) UserID() (uuid.UUID, error) {
+	return (&Claims{RegisteredClaims: c.RegisteredClaims}).UserID()
+}
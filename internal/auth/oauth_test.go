@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestVerifyPKCE(t *testing.T) {
+	// RFC 7636 Appendix B
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	if err := VerifyPKCE(verifier, challenge); err != nil {
+		t.Errorf("Expected RFC 7636 example to verify: %v", err)
+	}
+	if err := VerifyPKCE(strings.Replace(verifier, "d", "e", 1), challenge); err == nil {
+		t.Error("Expected wrong verifier to fail")
+	}
+	if err := VerifyPKCE("short", challenge); err == nil {
+		t.Error("Expected short verifier to fail")
+	}
+}
+
+func TestOAuthJWT(t *testing.T) {
+	ks, err := LoadKeySet(t.TempDir())
+	if err != nil {
+		t.Fatalf("LoadKeySet failed: %v", err)
+	}
+	userID, sessionID, clientID := uuid.New(), uuid.New(), uuid.New()
+	scopes := []string{"chirps:read", "chirps:write"}
+
+	token, err := ks.MakeOAuthJWT(userID, sessionID, clientID, scopes, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeOAuthJWT failed: %v", err)
+	}
+
+	claims, err := ks.ParseOAuthJWT(token)
+	if err != nil {
+		t.Fatalf("ParseOAuthJWT failed: %v", err)
+	}
+	if got, _ := claims.UserID(); got != userID {
+		t.Errorf("Expected user %s, got %s", userID, got)
+	}
+	if claims.SessionUUID() != sessionID || claims.ClientUUID() != clientID {
+		t.Error("Expected session and client to round trip")
+	}
+	if !slices.Equal(claims.Scopes(), scopes) {
+		t.Errorf("Expected scopes %v, got %v", scopes, claims.Scopes())
+	}
+
+	// Delegated tokens must never pass as first-party access tokens, and
+	// vice versa.
+	if _, err := ks.ParseJWT(token); err == nil {
+		t.Error("Expected ParseJWT to reject an OAuth access token")
+	}
+	firstParty, err := ks.MakeJWT(userID, sessionID, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+	if _, err := ks.ParseOAuthJWT(firstParty); err == nil {
+		t.Error("Expected ParseOAuthJWT to reject a first-party access token")
+	}
+}
@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrNoPassword is returned when verifying against an account that has no
+// password set, such as the 'unset' placeholder migration 003 gave existing
+// users.
+var ErrNoPassword = errors.New("no password set")
+
+// ErrPasswordMismatch is returned when a password doesn't match its hash.
+var ErrPasswordMismatch = errors.New("password does not match")
+
+// PasswordHasher hashes passwords into PHC strings
+// ($id$v=..$params$salt$hash) and verifies them.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	// Verify checks password against encoded. needsRehash reports whether
+	// encoded was produced by an older algorithm or with different parameters
+	// and should be replaced by Hash(password) now that it is known.
+	Verify(password, encoded string) (needsRehash bool, err error)
+}
+
+// Argon2idParams are the cost parameters for argon2id. Memory is in KiB.
+type Argon2idParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2idParams follow the OWASP recommendation of 19 MiB of memory
+// and two iterations.
+var DefaultArgon2idParams = Argon2idParams{
+	Memory:      19 * 1024,
+	Iterations:  2,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// Argon2idHasher hashes new passwords with argon2id and also verifies the
+// bcrypt hashes Chirpy used before, always flagging those for a rehash.
+type Argon2idHasher struct {
+	Params Argon2idParams
+}
+
+func NewArgon2idHasher(params Argon2idParams) *Argon2idHasher {
+	return &Argon2idHasher{Params: params}
+}
+
+var phcEncoding = base64.RawStdEncoding
+
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.Params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.Params.Iterations, h.Params.Memory, h.Params.Parallelism, h.Params.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.Params.Memory, h.Params.Iterations, h.Params.Parallelism,
+		phcEncoding.EncodeToString(salt), phcEncoding.EncodeToString(key)), nil
+}
+
+func (h *Argon2idHasher) Verify(password, encoded string) (bool, error) {
+	switch {
+	case encoded == "" || encoded == "unset":
+		// Do the work of a real comparison anyway so that response times
+		// don't reveal which accounts have no password.
+		h.Hash(password)
+		return false, ErrNoPassword
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		return h.verifyArgon2id(password, encoded)
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		if err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)); err != nil {
+			return false, ErrPasswordMismatch
+		}
+		return true, nil
+	default:
+		return false, errors.New("unrecognised password hash format")
+	}
+}
+
+func (h *Argon2idHasher) verifyArgon2id(password, encoded string) (bool, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return false, errors.New("malformed argon2id hash")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, errors.New("unsupported argon2id version")
+	}
+	var params Argon2idParams
+	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism)
+	if err != nil {
+		return false, errors.New("malformed argon2id parameters")
+	}
+	salt, err := phcEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, errors.New("malformed argon2id salt")
+	}
+	want, err := phcEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false, errors.New("malformed argon2id hash")
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(want))
+
+	got := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	if subtle.ConstantTimeCompare(got, want) != 1 {
+		return false, ErrPasswordMismatch
+	}
+	return params != h.Params, nil
+}
+
+// DefaultPasswordHasher is used by HashPassword and CheckPasswordHash.
+var DefaultPasswordHasher PasswordHasher = NewArgon2idHasher(DefaultArgon2idParams)
+
+func HashPassword(password string) (string, error) {
+	return DefaultPasswordHasher.Hash(password)
+}
+
+func CheckPasswordHash(password, hash string) error {
+	_, err := DefaultPasswordHasher.Verify(password, hash)
+	return err
+}
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// PasswordViolation is one reason a password was rejected. Code is stable
+// for clients to match on; Message is meant for people.
+type PasswordViolation struct {
+	Code    string
+	Message string
+}
+
+// PasswordPolicy decides which new passwords are acceptable.
+type PasswordPolicy struct {
+	// MinLength is counted in characters, not bytes.
+	MinLength int
+	// MinStrength is the lowest PasswordStrength score accepted, 0 to 4.
+	MinStrength int
+	// Breached, if set, is checked for passwords known from data breaches.
+	Breached BreachedPasswords
+}
+
+// DefaultPasswordPolicy follows NIST SP 800-63B: a minimum length and a
+// check against guessable passwords rather than composition rules.
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength:   8,
+	MinStrength: 2,
+}
+
+// Check returns every way password falls short of the policy for an account
+// with the given emails. An error means the breached password corpus couldn't
+// be queried.
+func (p PasswordPolicy) Check(password string, emails ...string) ([]PasswordViolation, error) {
+	var violations []PasswordViolation
+	if utf8.RuneCountInString(password) < p.MinLength {
+		violations = append(violations, PasswordViolation{
+			Code:    "too_short",
+			Message: "Password must be at least " + strconv.Itoa(p.MinLength) + " characters",
+		})
+	}
+	if containsEmail(password, emails) {
+		violations = append(violations, PasswordViolation{
+			Code:    "contains_email",
+			Message: "Password must not contain your email address",
+		})
+	}
+	if PasswordStrength(password, emails...) < p.MinStrength {
+		violations = append(violations, PasswordViolation{
+			Code:    "too_weak",
+			Message: "Password is too easy to guess",
+		})
+	}
+	if p.Breached != nil && password != "" {
+		breached, err := IsBreached(p.Breached, password)
+		if err != nil {
+			return nil, err
+		}
+		if breached {
+			violations = append(violations, PasswordViolation{
+				Code:    "breached",
+				Message: "Password has appeared in a data breach",
+			})
+		}
+	}
+	return violations, nil
+}
+
+// containsEmail reports whether password contains any of the emails, or the
+// part before the @ if that is long enough to be distinctive.
+func containsEmail(password string, emails []string) bool {
+	password = strings.ToLower(password)
+	for _, email := range emails {
+		email = strings.ToLower(strings.TrimSpace(email))
+		if email == "" {
+			continue
+		}
+		local, _, _ := strings.Cut(email, "@")
+		if strings.Contains(password, email) || (len(local) >= 3 && strings.Contains(password, local)) {
+			return true
+		}
+	}
+	return false
+}
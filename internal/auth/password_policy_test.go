@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestPasswordStrength(t *testing.T) {
+	tests := []struct {
+		password string
+		min, max int
+	}{
+		{"", 0, 0},
+		{"password", 0, 0},
+		{"aaaaaaaaaaaa", 0, 0},
+		{"abcdefghijkl", 0, 0},
+		{"Password123", 0, 1},
+		{"qwerty123456", 0, 1},
+		{"jane.doe2024", 0, 2},
+		{"Tr0ub4dor&3", 4, 4},
+		{"correcthorsebatterystaple", 4, 4},
+		{"x7#kQ9!vLm", 4, 4},
+	}
+
+	for _, tt := range tests {
+		got := PasswordStrength(tt.password, "jane.doe@example.com")
+		if got < tt.min || got > tt.max {
+			t.Errorf("PasswordStrength(%q) = %d, want between %d and %d", tt.password, got, tt.min, tt.max)
+		}
+	}
+}
+
+func TestPasswordPolicy_Check(t *testing.T) {
+	policy := PasswordPolicy{MinLength: 8, MinStrength: 2}
+
+	codes := func(password string, emails ...string) []string {
+		violations, err := policy.Check(password, emails...)
+		if err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		var codes []string
+		for _, v := range violations {
+			codes = append(codes, v.Code)
+		}
+		return codes
+	}
+
+	if got := codes("x7#kQ9!vLm", "jane@example.com"); len(got) != 0 {
+		t.Errorf("Expected strong password to pass, got %v", got)
+	}
+	if got := codes(""); !slices.Equal(got, []string{"too_short", "too_weak"}) {
+		t.Errorf("Expected empty password to be too short and too weak, got %v", got)
+	}
+	if got := codes("xX#janedoe#9Q", "JaneDoe@example.com"); !slices.Equal(got, []string{"contains_email"}) {
+		t.Errorf("Expected contains_email, got %v", got)
+	}
+}
+
+func TestPasswordPolicy_Breached(t *testing.T) {
+	corpus := writeBreachedCorpus(t, "hunter2-but-longer", "another leaked one", "x7#kQ9!vLm")
+	policy := PasswordPolicy{MinLength: 8, Breached: corpus}
+
+	violations, err := policy.Check("x7#kQ9!vLm")
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(violations) != 1 || violations[0].Code != "breached" {
+		t.Errorf("Expected breached, got %v", violations)
+	}
+
+	violations, err = policy.Check("not in the corpus")
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(violations) != 0 {
+		t.Errorf("Expected no violations, got %v", violations)
+	}
+}
+
+func TestBreachedPasswordFile_Range(t *testing.T) {
+	passwords := []string{"one", "two", "three", "four", "five"}
+	corpus := writeBreachedCorpus(t, passwords...)
+
+	for _, password := range passwords {
+		breached, err := IsBreached(corpus, password)
+		if err != nil {
+			t.Fatalf("IsBreached failed: %v", err)
+		}
+		if !breached {
+			t.Errorf("Expected %q to be found", password)
+		}
+	}
+	if breached, _ := IsBreached(corpus, "six"); breached {
+		t.Error("Expected six not to be found")
+	}
+	if _, err := corpus.Range("xyz"); err == nil {
+		t.Error("Expected invalid prefix to be rejected")
+	}
+}
+
+func TestOpenBreachedPasswordFile_Unsorted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	content := strings.Repeat("F", 40) + ":1\n" + strings.Repeat("0", 40) + ":1\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenBreachedPasswordFile(path); err == nil {
+		t.Error("Expected unsorted corpus to be rejected")
+	}
+}
+
+func TestOpenBreachedPasswordFile_BlankLines(t *testing.T) {
+	var hashes []string
+	for _, password := range []string{"one", "two", "three"} {
+		sum := sha1.Sum([]byte(password))
+		hashes = append(hashes, strings.ToUpper(hex.EncodeToString(sum[:]))+":7")
+	}
+	sort.Strings(hashes)
+	content := "\n" + hashes[0] + "\n   \n" + hashes[1] + "\r\n\t\r\n\n" + hashes[2] + "\n\n  "
+
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	corpus, err := OpenBreachedPasswordFile(path)
+	if err != nil {
+		t.Fatalf("Expected blank lines to be skipped, got %v", err)
+	}
+	defer corpus.Close()
+
+	for _, password := range []string{"one", "two", "three"} {
+		breached, err := IsBreached(corpus, password)
+		if err != nil {
+			t.Fatalf("IsBreached failed: %v", err)
+		}
+		if !breached {
+			t.Errorf("Expected %q to be found", password)
+		}
+	}
+	if breached, _ := IsBreached(corpus, "four"); breached {
+		t.Error("Expected four not to be found")
+	}
+}
+
+// writeBreachedCorpus writes passwords as a sorted HIBP-style file and opens it.
+func writeBreachedCorpus(t *testing.T, passwords ...string) *BreachedPasswordFile {
+	t.Helper()
+	var lines []string
+	for _, password := range passwords {
+		sum := sha1.Sum([]byte(password))
+		lines = append(lines, strings.ToUpper(hex.EncodeToString(sum[:]))+":42")
+	}
+	sort.Strings(lines)
+
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\r\n")), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	corpus, err := OpenBreachedPasswordFile(path)
+	if err != nil {
+		t.Fatalf("OpenBreachedPasswordFile failed: %v", err)
+	}
+	t.Cleanup(func() { corpus.Close() })
+	return corpus
+}
@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PasswordStrength estimates how hard password is to guess on the 0 to 4
+// scale used by zxcvbn: 0 falls to under a thousand guesses, 4 needs more
+// than ten billion. Like zxcvbn it splits the password into the cheapest
+// patterns an attacker would try (common passwords, words from userInputs,
+// repeated characters, runs like "abc" or "321") and brute force for the
+// rest, but with a much smaller dictionary.
+func PasswordStrength(password string, userInputs ...string) int {
+	bits := passwordGuessBits(password, userInputTokens(userInputs))
+	switch {
+	case bits < math.Log2(1e3):
+		return 0
+	case bits < math.Log2(1e6):
+		return 1
+	case bits < math.Log2(1e8):
+		return 2
+	case bits < math.Log2(1e10):
+		return 3
+	default:
+		return 4
+	}
+}
+
+// minPatternLen is the shortest match worth treating as a pattern.
+const minPatternLen = 3
+
+func passwordGuessBits(password string, inputs []string) float64 {
+	runes := []rune(password)
+	lower := []rune(strings.ToLower(password))
+	var bits float64
+	for i := 0; i < len(runes); {
+		n, cost := bestPattern(runes, lower, i, inputs)
+		if n == 0 {
+			n, cost = 1, math.Log2(charsetSize(runes[i]))
+		}
+		bits += cost
+		i += n
+	}
+	return bits
+}
+
+// bestPattern returns the length and guess cost in bits of the longest
+// pattern starting at i, or 0 if none does.
+func bestPattern(runes, lower []rune, i int, inputs []string) (int, float64) {
+	bestLen, bestCost := 0, 0.0
+	consider := func(n int, cost float64) {
+		if n >= minPatternLen && n > bestLen {
+			bestLen, bestCost = n, cost
+		}
+	}
+
+	rest := string(lower[i:])
+	for rank, word := range commonPasswords {
+		if strings.HasPrefix(rest, word) {
+			n := len([]rune(word))
+			consider(n, math.Log2(float64(rank+1))+capitalizationBits(runes[i:i+n]))
+		}
+	}
+	for _, word := range inputs {
+		if strings.HasPrefix(rest, word) {
+			n := len([]rune(word))
+			consider(n, 1+capitalizationBits(runes[i:i+n]))
+		}
+	}
+
+	// Repeated character: "aaaa"
+	n := 1
+	for i+n < len(runes) && runes[i+n] == runes[i] {
+		n++
+	}
+	consider(n, math.Log2(charsetSize(runes[i]))+math.Log2(float64(n)))
+
+	// Sequence with a step of one either way: "abcd", "9876"
+	if i+1 < len(lower) {
+		step := lower[i+1] - lower[i]
+		if step == 1 || step == -1 {
+			n := 2
+			for i+n < len(lower) && lower[i+n]-lower[i+n-1] == step {
+				n++
+			}
+			consider(n, math.Log2(charsetSize(lower[i]))+math.Log2(float64(n))+1)
+		}
+	}
+	return bestLen, bestCost
+}
+
+// capitalizationBits is the extra cost of the usual capitalisations of a
+// word: none, the first letter, or all of it.
+func capitalizationBits(word []rune) float64 {
+	for _, r := range word {
+		if unicode.IsUpper(r) {
+			return 1
+		}
+	}
+	return 0
+}
+
+func charsetSize(r rune) float64 {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		return 26
+	case r >= '0' && r <= '9':
+		return 10
+	case r < utf8.RuneSelf && unicode.IsPrint(r):
+		return 33
+	default:
+		return 100
+	}
+}
+
+// userInputTokens splits emails and similar inputs into the words an
+// attacker who knows them would try.
+func userInputTokens(inputs []string) []string {
+	var tokens []string
+	for _, input := range inputs {
+		fields := strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for _, f := range fields {
+			if len([]rune(f)) >= minPatternLen {
+				tokens = append(tokens, f)
+			}
+		}
+	}
+	return tokens
+}
+
+// commonPasswords are among the most used passwords in public breach
+// corpora, most common first.
+var commonPasswords = []string{
+	"123456", "password", "12345678", "qwerty", "123456789", "12345", "1234",
+	"111111", "1234567", "dragon", "123123", "baseball", "abc123", "football",
+	"monkey", "letmein", "shadow", "master", "696969", "mustang", "666666",
+	"qwertyuiop", "123321", "1234567890", "superman", "654321",
+	"1qaz2wsx", "7777777", "qazwsx", "jordan", "jennifer",
+	"123qwe", "121212", "killer", "trustno1", "hunter", "harley", "zxcvbnm",
+	"asdfgh", "buster", "andrew", "batman", "soccer", "tigger", "charlie",
+	"robert", "thomas", "hockey", "ranger", "daniel", "starwars",
+	"112233", "george", "computer", "michelle", "jessica", "pepper",
+	"zxcvbn", "555555", "131313", "freedom", "777777", "pass", "maggie",
+	"159753", "aaaaaa", "ginger", "princess", "joshua", "cheese", "amanda",
+	"summer", "love", "ashley", "nicole", "chelsea", "biteme", "matthew",
+	"access", "yankees", "987654321", "dallas", "austin", "thunder", "taylor",
+	"matrix", "welcome", "sunshine", "iloveyou", "admin", "login", "passw0rd",
+	"qwerty123", "password1", "hello", "secret", "monday", "chirpy",
+}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testArgon2idParams keep the tests fast.
+var testArgon2idParams = Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestArgon2idHasher_RoundTrip(t *testing.T) {
+	h := NewArgon2idHasher(testArgon2idParams)
+
+	hash, err := h.Hash("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("Hash failed: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Errorf("Unexpected PHC string: %s", hash)
+	}
+
+	needsRehash, err := h.Verify("correct horse battery staple", hash)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if needsRehash {
+		t.Error("Expected no rehash for current parameters")
+	}
+
+	if _, err := h.Verify("wrong", hash); !errors.Is(err, ErrPasswordMismatch) {
+		t.Errorf("Expected ErrPasswordMismatch, got %v", err)
+	}
+}
+
+func TestArgon2idHasher_LongPasswords(t *testing.T) {
+	// bcrypt ignores everything after 72 bytes; argon2id must not.
+	h := NewArgon2idHasher(testArgon2idParams)
+	long := strings.Repeat("a", 72)
+
+	hash, err := h.Hash(long + "1")
+	if err != nil {
+		t.Fatalf("Hash failed: %v", err)
+	}
+	if _, err := h.Verify(long+"2", hash); err == nil {
+		t.Error("Expected passwords differing after 72 bytes to be distinguished")
+	}
+}
+
+func TestArgon2idHasher_RehashOnParameterChange(t *testing.T) {
+	old := NewArgon2idHasher(testArgon2idParams)
+	hash, err := old.Hash("password")
+	if err != nil {
+		t.Fatalf("Hash failed: %v", err)
+	}
+
+	params := testArgon2idParams
+	params.Iterations = 2
+	needsRehash, err := NewArgon2idHasher(params).Verify("password", hash)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if !needsRehash {
+		t.Error("Expected rehash after parameters changed")
+	}
+}
+
+func TestArgon2idHasher_VerifiesBcrypt(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("bcrypt failed: %v", err)
+	}
+	h := NewArgon2idHasher(testArgon2idParams)
+
+	needsRehash, err := h.Verify("password", string(legacy))
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if !needsRehash {
+		t.Error("Expected bcrypt hashes to need a rehash")
+	}
+	if _, err := h.Verify("wrong", string(legacy)); !errors.Is(err, ErrPasswordMismatch) {
+		t.Errorf("Expected ErrPasswordMismatch, got %v", err)
+	}
+}
+
+func TestArgon2idHasher_Unset(t *testing.T) {
+	h := NewArgon2idHasher(testArgon2idParams)
+	for _, encoded := range []string{"unset", ""} {
+		if _, err := h.Verify("unset", encoded); !errors.Is(err, ErrNoPassword) {
+			t.Errorf("Expected ErrNoPassword for %q, got %v", encoded, err)
+		}
+	}
+	if _, err := h.Verify("password", "$md5$nope"); err == nil {
+		t.Error("Expected unknown hash formats to be rejected")
+	}
+}
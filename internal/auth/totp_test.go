@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"encoding/base32"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 seed from RFC 6238 Appendix B.
+var rfc6238Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCode_RFC6238Vectors(t *testing.T) {
+	// The RFC lists 8-digit codes; a 6-digit code is the last 6 of them.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := TOTPCode(rfc6238Secret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("TOTPCode failed: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("At %d: expected %s, got %s", tt.unix, tt.want, got)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret := GenerateTOTPSecret()
+	now := time.Unix(1700000000, 0)
+
+	code, err := TOTPCode(secret, now)
+	if err != nil {
+		t.Fatalf("TOTPCode failed: %v", err)
+	}
+	counter, err := ValidateTOTP(secret, code, now)
+	if err != nil {
+		t.Fatalf("ValidateTOTP failed: %v", err)
+	}
+	if counter != now.Unix()/30 {
+		t.Errorf("Expected counter %d, got %d", now.Unix()/30, counter)
+	}
+
+	// One period of drift either way is accepted
+	if _, err := ValidateTOTP(secret, code, now.Add(30*time.Second)); err != nil {
+		t.Errorf("Expected code to be accepted one period later: %v", err)
+	}
+	if _, err := ValidateTOTP(secret, code, now.Add(-30*time.Second)); err != nil {
+		t.Errorf("Expected code to be accepted one period earlier: %v", err)
+	}
+	// Two periods is too much
+	if _, err := ValidateTOTP(secret, code, now.Add(90*time.Second)); err == nil {
+		t.Error("Expected code to be rejected three periods later")
+	}
+
+	for _, bad := range []string{"", "12345", "1234567", "abcdef"} {
+		if _, err := ValidateTOTP(secret, bad, now); err == nil {
+			t.Errorf("Expected code %q to be rejected", bad)
+		}
+	}
+}
+
+func TestValidateTOTP_InvalidSecret(t *testing.T) {
+	if _, err := ValidateTOTP("not base32!", "123456", time.Now()); err == nil {
+		t.Error("Expected error for invalid secret")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("JBSWY3DPEHPK3PXP", "Chirpy", "user@example.com")
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("url.Parse failed: %v", err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" {
+		t.Errorf("Unexpected URI: %s", uri)
+	}
+	if u.Path != "/Chirpy:user@example.com" {
+		t.Errorf("Unexpected label: %s", u.Path)
+	}
+	if u.Query().Get("secret") != "JBSWY3DPEHPK3PXP" || u.Query().Get("issuer") != "Chirpy" {
+		t.Errorf("Unexpected query: %s", u.RawQuery)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes := GenerateRecoveryCodes(10)
+	if len(codes) != 10 {
+		t.Fatalf("Expected 10 codes, got %d", len(codes))
+	}
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 19 || strings.Count(code, "-") != 3 {
+			t.Errorf("Unexpected code format: %s", code)
+		}
+		if seen[code] {
+			t.Errorf("Duplicate code: %s", code)
+		}
+		seen[code] = true
+	}
+
+	// Codes are matched however the user types them
+	want := HashRecoveryCode(codes[0])
+	typed := strings.ToUpper(strings.ReplaceAll(codes[0], "-", " "))
+	if HashRecoveryCode(typed) != want {
+		t.Error("Expected recovery code hash to ignore case, spaces and dashes")
+	}
+}
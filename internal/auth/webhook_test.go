@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	const secret = "whsec_test"
+	body := []byte(`{"event":"user.upgraded","data":{"user_id":"3311741c-680c-4546-99f3-fc9efac2036c"}}`)
+	now := time.Now()
+	valid := SignWebhook(secret, body, now)
+
+	tests := []struct {
+		name    string
+		secret  string
+		header  string
+		body    []byte
+		wantErr error
+	}{
+		{name: "valid", secret: secret, header: valid, body: body},
+		{name: "rolled secret", secret: secret, header: SignWebhook("whsec_old", body, now) + ",v1=" + webhookMAC(secret, strconv.FormatInt(now.Unix(), 10), body), body: body},
+		{name: "wrong secret", secret: "whsec_other", header: valid, body: body, wantErr: ErrInvalidSignature},
+		{name: "tampered body", secret: secret, header: valid, body: []byte(`{"event":"user.downgraded"}`), wantErr: ErrInvalidSignature},
+		{name: "stale", secret: secret, header: SignWebhook(secret, body, now.Add(-time.Hour)), body: body, wantErr: ErrStaleSignature},
+		{name: "future", secret: secret, header: SignWebhook(secret, body, now.Add(time.Hour)), body: body, wantErr: ErrStaleSignature},
+		{name: "missing timestamp", secret: secret, header: "v1=" + webhookMAC(secret, strconv.FormatInt(now.Unix(), 10), body), body: body, wantErr: ErrInvalidSignature},
+		{name: "empty", secret: secret, header: "", body: body, wantErr: ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyWebhookSignature(tt.secret, tt.header, tt.body, now, 5*time.Minute)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: access_token_revocations.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const deleteExpiredAccessTokenRevocations = `-- name: DeleteExpiredAccessTokenRevocations :exec
+DELETE FROM access_token_revocations
+WHERE expires_at <= NOW()
+`
+
+func (q *Queries) DeleteExpiredAccessTokenRevocations(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteExpiredAccessTokenRevocations)
+	return err
+}
+
+const listActiveAccessTokenRevocations = `-- name: ListActiveAccessTokenRevocations :many
+SELECT kind, subject, revoked_at, expires_at FROM access_token_revocations
+WHERE expires_at > NOW()
+`
+
+func (q *Queries) ListActiveAccessTokenRevocations(ctx context.Context) ([]AccessTokenRevocation, error) {
+	rows, err := q.db.QueryContext(ctx, listActiveAccessTokenRevocations)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AccessTokenRevocation
+	for rows.Next() {
+		var i AccessTokenRevocation
+		if err := rows.Scan(
+			&i.Kind,
+			&i.Subject,
+			&i.RevokedAt,
+			&i.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeAccessTokens = `-- name: RevokeAccessTokens :exec
+INSERT INTO access_token_revocations (kind, subject, revoked_at, expires_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT (kind, subject) DO UPDATE SET
+revoked_at = EXCLUDED.revoked_at,
+expires_at = EXCLUDED.expires_at
+`
+
+type RevokeAccessTokensParams struct {
+	Kind      string
+	Subject   uuid.UUID
+	RevokedAt time.Time
+	ExpiresAt time.Time
+}
+
+func (q *Queries) RevokeAccessTokens(ctx context.Context, arg RevokeAccessTokensParams) error {
+	_, err := q.db.ExecContext(ctx, revokeAccessTokens,
+		arg.Kind,
+		arg.Subject,
+		arg.RevokedAt,
+		arg.ExpiresAt,
+	)
+	return err
+}
@@ -0,0 +1,68 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: chirp_posts.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const getChirpRateWindow = `-- name: GetChirpRateWindow :one
+SELECT COUNT(*)::int AS count, COALESCE(MIN(created_at), NOW())::timestamp AS oldest
+FROM chirp_posts
+WHERE user_id = $1
+AND created_at > $2::timestamp
+`
+
+type GetChirpRateWindowParams struct {
+	UserID uuid.UUID
+	Since  time.Time
+}
+
+type GetChirpRateWindowRow struct {
+	Count  int32
+	Oldest time.Time
+}
+
+func (q *Queries) GetChirpRateWindow(ctx context.Context, arg GetChirpRateWindowParams) (GetChirpRateWindowRow, error) {
+	row := q.db.QueryRowContext(ctx, getChirpRateWindow, arg.UserID, arg.Since)
+	var i GetChirpRateWindowRow
+	err := row.Scan(&i.Count, &i.Oldest)
+	return i, err
+}
+
+const pruneChirpPosts = `-- name: PruneChirpPosts :exec
+DELETE FROM chirp_posts
+WHERE user_id = $1
+AND created_at <= $2::timestamp
+`
+
+type PruneChirpPostsParams struct {
+	UserID uuid.UUID
+	Before time.Time
+}
+
+func (q *Queries) PruneChirpPosts(ctx context.Context, arg PruneChirpPostsParams) error {
+	_, err := q.db.ExecContext(ctx, pruneChirpPosts, arg.UserID, arg.Before)
+	return err
+}
+
+const recordChirpPost = `-- name: RecordChirpPost :exec
+INSERT INTO chirp_posts (user_id, created_at)
+VALUES ($1, $2)
+`
+
+type RecordChirpPostParams struct {
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+func (q *Queries) RecordChirpPost(ctx context.Context, arg RecordChirpPostParams) error {
+	_, err := q.db.ExecContext(ctx, recordChirpPost, arg.UserID, arg.CreatedAt)
+	return err
+}
@@ -0,0 +1,229 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: data_exports.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const claimDataExport = `-- name: ClaimDataExport :one
+UPDATE data_exports SET status = 'running', updated_at = NOW()
+WHERE id = (
+    SELECT id FROM data_exports
+    WHERE status = 'pending'
+    OR (status = 'running' AND updated_at < $1::timestamp)
+    ORDER BY created_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, created_at, updated_at, user_id, status, error, completed_at, expires_at
+`
+
+type ClaimDataExportRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	Status      string
+	Error       sql.NullString
+	CompletedAt sql.NullTime
+	ExpiresAt   sql.NullTime
+}
+
+// Takes the oldest pending export, or one whose worker seems to have died.
+func (q *Queries) ClaimDataExport(ctx context.Context, staleBefore time.Time) (ClaimDataExportRow, error) {
+	row := q.db.QueryRowContext(ctx, claimDataExport, staleBefore)
+	var i ClaimDataExportRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Status,
+		&i.Error,
+		&i.CompletedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const completeDataExport = `-- name: CompleteDataExport :exec
+UPDATE data_exports SET status = 'complete', archive = $2, completed_at = NOW(), expires_at = $3, updated_at = NOW()
+WHERE id = $1
+`
+
+type CompleteDataExportParams struct {
+	ID        uuid.UUID
+	Archive   []byte
+	ExpiresAt sql.NullTime
+}
+
+func (q *Queries) CompleteDataExport(ctx context.Context, arg CompleteDataExportParams) error {
+	_, err := q.db.ExecContext(ctx, completeDataExport, arg.ID, arg.Archive, arg.ExpiresAt)
+	return err
+}
+
+const createDataExport = `-- name: CreateDataExport :one
+INSERT INTO data_exports (id, created_at, updated_at, user_id)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1
+)
+RETURNING id, created_at, updated_at, user_id, status, error, completed_at, expires_at
+`
+
+type CreateDataExportRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	Status      string
+	Error       sql.NullString
+	CompletedAt sql.NullTime
+	ExpiresAt   sql.NullTime
+}
+
+func (q *Queries) CreateDataExport(ctx context.Context, userID uuid.UUID) (CreateDataExportRow, error) {
+	row := q.db.QueryRowContext(ctx, createDataExport, userID)
+	var i CreateDataExportRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Status,
+		&i.Error,
+		&i.CompletedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const deleteExpiredDataExports = `-- name: DeleteExpiredDataExports :exec
+DELETE FROM data_exports
+WHERE expires_at <= NOW()
+`
+
+func (q *Queries) DeleteExpiredDataExports(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deleteExpiredDataExports)
+	return err
+}
+
+const failDataExport = `-- name: FailDataExport :exec
+UPDATE data_exports SET status = 'failed', error = $2, completed_at = NOW(), updated_at = NOW()
+WHERE id = $1
+`
+
+type FailDataExportParams struct {
+	ID    uuid.UUID
+	Error sql.NullString
+}
+
+func (q *Queries) FailDataExport(ctx context.Context, arg FailDataExportParams) error {
+	_, err := q.db.ExecContext(ctx, failDataExport, arg.ID, arg.Error)
+	return err
+}
+
+const getDataExportArchive = `-- name: GetDataExportArchive :one
+SELECT archive FROM data_exports
+WHERE id = $1
+AND user_id = $2
+AND status = 'complete'
+AND expires_at > NOW()
+`
+
+type GetDataExportArchiveParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) GetDataExportArchive(ctx context.Context, arg GetDataExportArchiveParams) ([]byte, error) {
+	row := q.db.QueryRowContext(ctx, getDataExportArchive, arg.ID, arg.UserID)
+	var archive []byte
+	err := row.Scan(&archive)
+	return archive, err
+}
+
+const getDataExportStatus = `-- name: GetDataExportStatus :one
+SELECT id, created_at, updated_at, user_id, status, error, completed_at, expires_at
+FROM data_exports
+WHERE id = $1
+AND user_id = $2
+`
+
+type GetDataExportStatusParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+type GetDataExportStatusRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	Status      string
+	Error       sql.NullString
+	CompletedAt sql.NullTime
+	ExpiresAt   sql.NullTime
+}
+
+func (q *Queries) GetDataExportStatus(ctx context.Context, arg GetDataExportStatusParams) (GetDataExportStatusRow, error) {
+	row := q.db.QueryRowContext(ctx, getDataExportStatus, arg.ID, arg.UserID)
+	var i GetDataExportStatusRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Status,
+		&i.Error,
+		&i.CompletedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const getUnfinishedDataExport = `-- name: GetUnfinishedDataExport :one
+SELECT id, created_at, updated_at, user_id, status, error, completed_at, expires_at
+FROM data_exports
+WHERE user_id = $1
+AND status IN ('pending', 'running')
+ORDER BY created_at DESC
+LIMIT 1
+`
+
+type GetUnfinishedDataExportRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	Status      string
+	Error       sql.NullString
+	CompletedAt sql.NullTime
+	ExpiresAt   sql.NullTime
+}
+
+func (q *Queries) GetUnfinishedDataExport(ctx context.Context, userID uuid.UUID) (GetUnfinishedDataExportRow, error) {
+	row := q.db.QueryRowContext(ctx, getUnfinishedDataExport, userID)
+	var i GetUnfinishedDataExportRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Status,
+		&i.Error,
+		&i.CompletedAt,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
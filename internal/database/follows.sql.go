@@ -0,0 +1,207 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: follows.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const countFollowers = `-- name: CountFollowers :one
+SELECT COUNT(*)::int FROM follows
+WHERE followee_id = $1
+`
+
+func (q *Queries) CountFollowers(ctx context.Context, followeeID uuid.UUID) (int32, error) {
+	row := q.db.QueryRowContext(ctx, countFollowers, followeeID)
+	var column_1 int32
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const followUser = `-- name: FollowUser :execrows
+INSERT INTO follows (follower_id, followee_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING
+`
+
+type FollowUserParams struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+}
+
+func (q *Queries) FollowUser(ctx context.Context, arg FollowUserParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, followUser, arg.FollowerID, arg.FolloweeID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const listFollowers = `-- name: ListFollowers :many
+SELECT users.id, users.created_at, users.updated_at, users.email, users.hashed_password, users.email_verified_at, users.pending_email, users.role, users.suspended_at, users.is_chirpy_red, users.chirpy_red_until, users.delete_after, users.handle, users.display_name, users.bio, users.avatar_url, users.location, users.fan_out_on_write, follows.created_at AS followed_at
+FROM follows
+JOIN users ON users.id = follows.follower_id
+WHERE follows.followee_id = $1
+AND users.suspended_at IS NULL
+AND users.delete_after IS NULL
+AND (follows.created_at, follows.follower_id) < ($2::timestamp, $3::uuid)
+ORDER BY follows.created_at DESC, follows.follower_id DESC
+LIMIT $4
+`
+
+type ListFollowersParams struct {
+	UserID          uuid.UUID
+	CursorCreatedAt time.Time
+	CursorID        uuid.UUID
+	Limit           int32
+}
+
+type ListFollowersRow struct {
+	User       User
+	FollowedAt time.Time
+}
+
+func (q *Queries) ListFollowers(ctx context.Context, arg ListFollowersParams) ([]ListFollowersRow, error) {
+	rows, err := q.db.QueryContext(ctx, listFollowers,
+		arg.UserID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListFollowersRow
+	for rows.Next() {
+		var i ListFollowersRow
+		if err := rows.Scan(
+			&i.User.ID,
+			&i.User.CreatedAt,
+			&i.User.UpdatedAt,
+			&i.User.Email,
+			&i.User.HashedPassword,
+			&i.User.EmailVerifiedAt,
+			&i.User.PendingEmail,
+			&i.User.Role,
+			&i.User.SuspendedAt,
+			&i.User.IsChirpyRed,
+			&i.User.ChirpyRedUntil,
+			&i.User.DeleteAfter,
+			&i.User.Handle,
+			&i.User.DisplayName,
+			&i.User.Bio,
+			&i.User.AvatarUrl,
+			&i.User.Location,
+			&i.User.FanOutOnWrite,
+			&i.FollowedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFollowing = `-- name: ListFollowing :many
+SELECT users.id, users.created_at, users.updated_at, users.email, users.hashed_password, users.email_verified_at, users.pending_email, users.role, users.suspended_at, users.is_chirpy_red, users.chirpy_red_until, users.delete_after, users.handle, users.display_name, users.bio, users.avatar_url, users.location, users.fan_out_on_write, follows.created_at AS followed_at
+FROM follows
+JOIN users ON users.id = follows.followee_id
+WHERE follows.follower_id = $1
+AND users.suspended_at IS NULL
+AND users.delete_after IS NULL
+AND (follows.created_at, follows.followee_id) < ($2::timestamp, $3::uuid)
+ORDER BY follows.created_at DESC, follows.followee_id DESC
+LIMIT $4
+`
+
+type ListFollowingParams struct {
+	UserID          uuid.UUID
+	CursorCreatedAt time.Time
+	CursorID        uuid.UUID
+	Limit           int32
+}
+
+type ListFollowingRow struct {
+	User       User
+	FollowedAt time.Time
+}
+
+func (q *Queries) ListFollowing(ctx context.Context, arg ListFollowingParams) ([]ListFollowingRow, error) {
+	rows, err := q.db.QueryContext(ctx, listFollowing,
+		arg.UserID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListFollowingRow
+	for rows.Next() {
+		var i ListFollowingRow
+		if err := rows.Scan(
+			&i.User.ID,
+			&i.User.CreatedAt,
+			&i.User.UpdatedAt,
+			&i.User.Email,
+			&i.User.HashedPassword,
+			&i.User.EmailVerifiedAt,
+			&i.User.PendingEmail,
+			&i.User.Role,
+			&i.User.SuspendedAt,
+			&i.User.IsChirpyRed,
+			&i.User.ChirpyRedUntil,
+			&i.User.DeleteAfter,
+			&i.User.Handle,
+			&i.User.DisplayName,
+			&i.User.Bio,
+			&i.User.AvatarUrl,
+			&i.User.Location,
+			&i.User.FanOutOnWrite,
+			&i.FollowedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const unfollowUser = `-- name: UnfollowUser :execrows
+DELETE FROM follows
+WHERE follower_id = $1
+AND followee_id = $2
+`
+
+type UnfollowUserParams struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+}
+
+func (q *Queries) UnfollowUser(ctx context.Context, arg UnfollowUserParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, unfollowUser, arg.FollowerID, arg.FolloweeID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
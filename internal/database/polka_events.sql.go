@@ -0,0 +1,32 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: polka_events.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const recordPolkaEvent = `-- name: RecordPolkaEvent :execrows
+INSERT INTO polka_events (id, event, user_id)
+VALUES ($1, $2, $3)
+ON CONFLICT (id) DO NOTHING
+`
+
+type RecordPolkaEventParams struct {
+	ID     string
+	Event  string
+	UserID uuid.UUID
+}
+
+func (q *Queries) RecordPolkaEvent(ctx context.Context, arg RecordPolkaEventParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, recordPolkaEvent, arg.ID, arg.Event, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
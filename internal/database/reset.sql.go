@@ -7,13 +7,15 @@ package database
 
 import (
 	"context"
+
+	"github.com/google/uuid"
 )
 
 const reset = `-- name: Reset :exec
-DELETE FROM users
+DELETE FROM users WHERE id <> $1
 `
 
-func (q *Queries) Reset(ctx context.Context) error {
-	_, err := q.db.ExecContext(ctx, reset)
+func (q *Queries) Reset(ctx context.Context, keepUserID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, reset, keepUserID)
 	return err
 }
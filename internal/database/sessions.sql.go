@@ -0,0 +1,296 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: sessions.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const createClientSession = `-- name: CreateClientSession :one
+INSERT INTO sessions (id, created_at, updated_at, user_id, name, user_agent, ip, last_used_at, client_id, scopes)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    NOW(),
+    $5,
+    $6
+)
+RETURNING id, created_at, updated_at, user_id, name, user_agent, ip, last_used_at, revoked_at, client_id, scopes
+`
+
+type CreateClientSessionParams struct {
+	UserID    uuid.UUID
+	Name      string
+	UserAgent string
+	Ip        string
+	ClientID  uuid.NullUUID
+	Scopes    []string
+}
+
+func (q *Queries) CreateClientSession(ctx context.Context, arg CreateClientSessionParams) (Session, error) {
+	row := q.db.QueryRowContext(ctx, createClientSession,
+		arg.UserID,
+		arg.Name,
+		arg.UserAgent,
+		arg.Ip,
+		arg.ClientID,
+		pq.Array(arg.Scopes),
+	)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Name,
+		&i.UserAgent,
+		&i.Ip,
+		&i.LastUsedAt,
+		&i.RevokedAt,
+		&i.ClientID,
+		pq.Array(&i.Scopes),
+	)
+	return i, err
+}
+
+const createSession = `-- name: CreateSession :one
+INSERT INTO sessions (id, created_at, updated_at, user_id, name, user_agent, ip, last_used_at)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    NOW()
+)
+RETURNING id, created_at, updated_at, user_id, name, user_agent, ip, last_used_at, revoked_at, client_id, scopes
+`
+
+type CreateSessionParams struct {
+	UserID    uuid.UUID
+	Name      string
+	UserAgent string
+	Ip        string
+}
+
+func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error) {
+	row := q.db.QueryRowContext(ctx, createSession,
+		arg.UserID,
+		arg.Name,
+		arg.UserAgent,
+		arg.Ip,
+	)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Name,
+		&i.UserAgent,
+		&i.Ip,
+		&i.LastUsedAt,
+		&i.RevokedAt,
+		&i.ClientID,
+		pq.Array(&i.Scopes),
+	)
+	return i, err
+}
+
+const getSession = `-- name: GetSession :one
+SELECT id, created_at, updated_at, user_id, name, user_agent, ip, last_used_at, revoked_at, client_id, scopes FROM sessions
+WHERE id = $1
+`
+
+func (q *Queries) GetSession(ctx context.Context, id uuid.UUID) (Session, error) {
+	row := q.db.QueryRowContext(ctx, getSession, id)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Name,
+		&i.UserAgent,
+		&i.Ip,
+		&i.LastUsedAt,
+		&i.RevokedAt,
+		&i.ClientID,
+		pq.Array(&i.Scopes),
+	)
+	return i, err
+}
+
+const listActiveSessions = `-- name: ListActiveSessions :many
+SELECT id, created_at, updated_at, user_id, name, user_agent, ip, last_used_at, revoked_at, client_id, scopes FROM sessions
+WHERE sessions.user_id = $1
+AND sessions.revoked_at IS NULL
+AND EXISTS (
+    SELECT 1 FROM refresh_tokens
+    WHERE refresh_tokens.family_id = sessions.id
+    AND refresh_tokens.revoked_at IS NULL
+    AND refresh_tokens.expires_at > NOW()
+)
+ORDER BY last_used_at DESC
+`
+
+func (q *Queries) ListActiveSessions(ctx context.Context, userID uuid.UUID) ([]Session, error) {
+	rows, err := q.db.QueryContext(ctx, listActiveSessions, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Session
+	for rows.Next() {
+		var i Session
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Name,
+			&i.UserAgent,
+			&i.Ip,
+			&i.LastUsedAt,
+			&i.RevokedAt,
+			&i.ClientID,
+			pq.Array(&i.Scopes),
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const renameSession = `-- name: RenameSession :one
+UPDATE sessions SET name = $3,
+updated_at = NOW()
+WHERE id = $1
+AND user_id = $2
+AND revoked_at IS NULL
+RETURNING id, created_at, updated_at, user_id, name, user_agent, ip, last_used_at, revoked_at, client_id, scopes
+`
+
+type RenameSessionParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+	Name   string
+}
+
+func (q *Queries) RenameSession(ctx context.Context, arg RenameSessionParams) (Session, error) {
+	row := q.db.QueryRowContext(ctx, renameSession, arg.ID, arg.UserID, arg.Name)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Name,
+		&i.UserAgent,
+		&i.Ip,
+		&i.LastUsedAt,
+		&i.RevokedAt,
+		&i.ClientID,
+		pq.Array(&i.Scopes),
+	)
+	return i, err
+}
+
+const revokeAllSessions = `-- name: RevokeAllSessions :exec
+UPDATE sessions SET revoked_at = NOW(),
+updated_at = NOW()
+WHERE user_id = $1
+AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeAllSessions(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeAllSessions, userID)
+	return err
+}
+
+const revokeSession = `-- name: RevokeSession :one
+UPDATE sessions SET revoked_at = NOW(),
+updated_at = NOW()
+WHERE id = $1
+AND user_id = $2
+AND revoked_at IS NULL
+RETURNING id, created_at, updated_at, user_id, name, user_agent, ip, last_used_at, revoked_at, client_id, scopes
+`
+
+type RevokeSessionParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) RevokeSession(ctx context.Context, arg RevokeSessionParams) (Session, error) {
+	row := q.db.QueryRowContext(ctx, revokeSession, arg.ID, arg.UserID)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Name,
+		&i.UserAgent,
+		&i.Ip,
+		&i.LastUsedAt,
+		&i.RevokedAt,
+		&i.ClientID,
+		pq.Array(&i.Scopes),
+	)
+	return i, err
+}
+
+const touchSession = `-- name: TouchSession :one
+UPDATE sessions SET last_used_at = NOW(),
+user_agent = $2,
+ip = $3,
+updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, user_id, name, user_agent, ip, last_used_at, revoked_at, client_id, scopes
+`
+
+type TouchSessionParams struct {
+	ID        uuid.UUID
+	UserAgent string
+	Ip        string
+}
+
+func (q *Queries) TouchSession(ctx context.Context, arg TouchSessionParams) (Session, error) {
+	row := q.db.QueryRowContext(ctx, touchSession, arg.ID, arg.UserAgent, arg.Ip)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Name,
+		&i.UserAgent,
+		&i.Ip,
+		&i.LastUsedAt,
+		&i.RevokedAt,
+		&i.ClientID,
+		pq.Array(&i.Scopes),
+	)
+	return i, err
+}
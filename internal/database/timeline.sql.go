@@ -0,0 +1,276 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: timeline.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const backfillFollowerTimelines = `-- name: BackfillFollowerTimelines :exec
+INSERT INTO timeline_entries (user_id, chirp_id, author_id, created_at)
+SELECT follows.follower_id, recent.id, recent.user_id, recent.created_at
+FROM follows
+CROSS JOIN (
+    SELECT chirps.id, chirps.user_id, chirps.created_at FROM chirps
+    WHERE chirps.user_id = $1::uuid
+    AND chirps.deleted_at IS NULL
+    ORDER BY chirps.created_at DESC
+    LIMIT $2
+) recent
+WHERE follows.followee_id = $1
+ON CONFLICT DO NOTHING
+`
+
+type BackfillFollowerTimelinesParams struct {
+	AuthorID uuid.UUID
+	Limit    int32
+}
+
+// Copies an author's latest chirps into every follower's timeline.
+func (q *Queries) BackfillFollowerTimelines(ctx context.Context, arg BackfillFollowerTimelinesParams) error {
+	_, err := q.db.ExecContext(ctx, backfillFollowerTimelines, arg.AuthorID, arg.Limit)
+	return err
+}
+
+const backfillTimeline = `-- name: BackfillTimeline :exec
+INSERT INTO timeline_entries (user_id, chirp_id, author_id, created_at)
+SELECT $1, chirps.id, chirps.user_id, chirps.created_at
+FROM chirps
+WHERE chirps.user_id = $2::uuid
+AND chirps.deleted_at IS NULL
+ORDER BY chirps.created_at DESC
+LIMIT $3
+ON CONFLICT DO NOTHING
+`
+
+type BackfillTimelineParams struct {
+	UserID   uuid.UUID
+	AuthorID uuid.UUID
+	Limit    int32
+}
+
+// Copies an author's latest chirps into one follower's timeline.
+func (q *Queries) BackfillTimeline(ctx context.Context, arg BackfillTimelineParams) error {
+	_, err := q.db.ExecContext(ctx, backfillTimeline, arg.UserID, arg.AuthorID, arg.Limit)
+	return err
+}
+
+const enableFanOutOnWrite = `-- name: EnableFanOutOnWrite :execrows
+UPDATE users SET fan_out_on_write = TRUE, updated_at = NOW()
+WHERE id = $1
+AND NOT fan_out_on_write
+`
+
+func (q *Queries) EnableFanOutOnWrite(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, enableFanOutOnWrite, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const fanOutChirp = `-- name: FanOutChirp :exec
+INSERT INTO timeline_entries (user_id, chirp_id, author_id, created_at)
+SELECT follows.follower_id, $1, $2, $3
+FROM follows
+JOIN users ON users.id = follows.followee_id
+WHERE follows.followee_id = $2
+AND users.fan_out_on_write
+ON CONFLICT DO NOTHING
+`
+
+type FanOutChirpParams struct {
+	ChirpID   uuid.UUID
+	AuthorID  uuid.UUID
+	CreatedAt time.Time
+}
+
+// Adds a new chirp to the timelines of its author's followers, if the author
+// fans out on write.
+func (q *Queries) FanOutChirp(ctx context.Context, arg FanOutChirpParams) error {
+	_, err := q.db.ExecContext(ctx, fanOutChirp, arg.ChirpID, arg.AuthorID, arg.CreatedAt)
+	return err
+}
+
+const followsFanOutAuthor = `-- name: FollowsFanOutAuthor :one
+SELECT EXISTS (
+    SELECT 1 FROM follows
+    JOIN users ON users.id = follows.followee_id
+    WHERE follows.follower_id = $1
+    AND users.fan_out_on_write
+)::boolean AS follows_fan_out_author
+`
+
+// Whether any of the user's followees fans out on write, so that their
+// timeline has precomputed entries to read.
+func (q *Queries) FollowsFanOutAuthor(ctx context.Context, followerID uuid.UUID) (bool, error) {
+	row := q.db.QueryRowContext(ctx, followsFanOutAuthor, followerID)
+	var follows_fan_out_author bool
+	err := row.Scan(&follows_fan_out_author)
+	return follows_fan_out_author, err
+}
+
+const listPrecomputedTimeline = `-- name: ListPrecomputedTimeline :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to_id, chirps.conversation_id, chirps.reply_count, chirps.deleted_at, chirps.like_count, chirps.rechirp_of_id, chirps.quote_of_id, chirps.rechirp_count, chirps.quote_count FROM chirps
+JOIN (
+    (SELECT timeline_entries.chirp_id AS id
+    FROM timeline_entries
+    JOIN chirps ON chirps.id = timeline_entries.chirp_id
+    WHERE timeline_entries.user_id = $1
+    AND chirps.deleted_at IS NULL
+    AND (timeline_entries.created_at, timeline_entries.chirp_id) < ($2::timestamp, $3::uuid)
+    ORDER BY timeline_entries.created_at DESC, timeline_entries.chirp_id DESC
+    LIMIT $4)
+    UNION
+    (SELECT chirps.id
+    FROM chirps
+    WHERE (chirps.user_id = $1
+        OR chirps.user_id IN (
+            SELECT follows.followee_id FROM follows
+            JOIN users ON users.id = follows.followee_id
+            WHERE follows.follower_id = $1
+            AND NOT users.fan_out_on_write))
+    AND chirps.deleted_at IS NULL
+    AND (chirps.created_at, chirps.id) < ($2::timestamp, $3::uuid)
+    ORDER BY chirps.created_at DESC, chirps.id DESC
+    LIMIT $4)
+) page ON page.id = chirps.id
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT $4
+`
+
+type ListPrecomputedTimelineParams struct {
+	UserID          uuid.UUID
+	CursorCreatedAt time.Time
+	CursorID        uuid.UUID
+	Limit           int32
+}
+
+// Chirps by followees who fan out on write come from timeline_entries; the
+// caller's own and the other followees' are merged in at read time. Each
+// side is cut to the page size before merging.
+func (q *Queries) ListPrecomputedTimeline(ctx context.Context, arg ListPrecomputedTimelineParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listPrecomputedTimeline,
+		arg.UserID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyToID,
+			&i.ConversationID,
+			&i.ReplyCount,
+			&i.DeletedAt,
+			&i.LikeCount,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.RechirpCount,
+			&i.QuoteCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTimeline = `-- name: ListTimeline :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to_id, chirps.conversation_id, chirps.reply_count, chirps.deleted_at, chirps.like_count, chirps.rechirp_of_id, chirps.quote_of_id, chirps.rechirp_count, chirps.quote_count FROM chirps
+WHERE (chirps.user_id = $1::uuid
+    OR chirps.user_id IN (SELECT followee_id FROM follows WHERE follower_id = $1))
+AND chirps.deleted_at IS NULL
+AND (chirps.created_at, chirps.id) < ($2::timestamp, $3::uuid)
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT $4
+`
+
+type ListTimelineParams struct {
+	UserID          uuid.UUID
+	CursorCreatedAt time.Time
+	CursorID        uuid.UUID
+	Limit           int32
+}
+
+// Fan-out on read: the caller's own chirps and those of everyone they follow.
+func (q *Queries) ListTimeline(ctx context.Context, arg ListTimelineParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listTimeline,
+		arg.UserID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyToID,
+			&i.ConversationID,
+			&i.ReplyCount,
+			&i.DeletedAt,
+			&i.LikeCount,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.RechirpCount,
+			&i.QuoteCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const removeTimelineAuthor = `-- name: RemoveTimelineAuthor :exec
+DELETE FROM timeline_entries
+WHERE user_id = $1
+AND author_id = $2
+`
+
+type RemoveTimelineAuthorParams struct {
+	UserID   uuid.UUID
+	AuthorID uuid.UUID
+}
+
+func (q *Queries) RemoveTimelineAuthor(ctx context.Context, arg RemoveTimelineAuthorParams) error {
+	_, err := q.db.ExecContext(ctx, removeTimelineAuthor, arg.UserID, arg.AuthorID)
+	return err
+}
@@ -0,0 +1,75 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormat(t *testing.T) {
+	date := time.Date(2025, time.May, 1, 12, 0, 0, 0, time.UTC)
+	data, err := format("Chirpy <no-reply@chirpy.test>", Message{
+		To:      "user@example.com",
+		Subject: "Hello",
+		Body:    "line one\nline two",
+	}, date)
+	if err != nil {
+		t.Fatalf("format failed: %v", err)
+	}
+
+	msg := string(data)
+	for _, want := range []string{
+		"From: Chirpy <no-reply@chirpy.test>\r\n",
+		"To: user@example.com\r\n",
+		"Subject: Hello\r\n",
+		"Date: Thu, 01 May 2025 12:00:00 +0000\r\n",
+		"\r\n\r\nline one\r\nline two",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("Expected message to contain %q, got:\n%s", want, msg)
+		}
+	}
+}
+
+func TestFormat_HeaderInjection(t *testing.T) {
+	tests := []Message{
+		{To: "user@example.com\r\nBcc: victim@example.com", Subject: "Hi"},
+		{To: "user@example.com", Subject: "Hi\nBcc: victim@example.com"},
+	}
+	for _, msg := range tests {
+		if _, err := format("no-reply@chirpy.test", msg, time.Now()); err == nil {
+			t.Errorf("Expected error for %+v", msg)
+		}
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m := &FileMailer{Dir: dir, From: "no-reply@chirpy.test"}
+
+	for i := 0; i < 2; i++ {
+		err := m.Send(context.Background(), Message{
+			To:      "user@example.com",
+			Subject: "Reset your password",
+			Body:    "token",
+		})
+		if err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(files))
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if !strings.Contains(string(data), "Subject: Reset your password") {
+		t.Errorf("Unexpected message:\n%s", data)
+	}
+}
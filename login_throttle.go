@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/mvusic07/Chirpy/internal/auth"
+	"github.com/mvusic07/Chirpy/internal/database"
+)
+
+// throttlePolicy allows freeAttempts failed logins, then locks the key for
+// baseDelay, doubling with every further failure up to maxDelay. Counters
+// start over once a key has had no failures for window.
+type throttlePolicy struct {
+	freeAttempts int32
+	baseDelay    time.Duration
+	maxDelay     time.Duration
+	window       time.Duration
+}
+
+var (
+	// Guessing one account's password gets slow quickly.
+	accountThrottle = throttlePolicy{
+		freeAttempts: 5,
+		baseDelay:    30 * time.Second,
+		maxDelay:     time.Hour,
+		window:       time.Hour,
+	}
+	// Many users can share an address behind NAT, so an IP gets more room
+	// before it is locked out across every account.
+	ipThrottle = throttlePolicy{
+		freeAttempts: 50,
+		baseDelay:    time.Minute,
+		maxDelay:     time.Hour,
+		window:       time.Hour,
+	}
+)
+
+// lockout returns how long a key is locked after its failures-th failure.
+func (p throttlePolicy) lockout(failures int32) time.Duration {
+	if failures <= p.freeAttempts {
+		return 0
+	}
+	delay := p.baseDelay
+	for i := p.freeAttempts + 1; i < failures; i++ {
+		delay *= 2
+		if delay >= p.maxDelay {
+			return p.maxDelay
+		}
+	}
+	return delay
+}
+
+// accountThrottleKey keys the throttle by normalized email, so spelling the
+// address differently doesn't get a fresh set of attempts.
+func accountThrottleKey(email string) string {
+	if normalized, err := auth.NormalizeEmail(email); err == nil {
+		return "account:" + normalized
+	}
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+// loginRetryAfter returns how long until none of keys is locked out, or zero
+// if a login may be attempted now.
+func (cfg *apiConfig) loginRetryAfter(ctx context.Context, keys ...string) (time.Duration, error) {
+	throttles, err := cfg.db.GetLoginThrottles(ctx, keys)
+	if err != nil {
+		return 0, err
+	}
+	var retryAfter time.Duration
+	now := time.Now().UTC()
+	for _, throttle := range throttles {
+		if throttle.LockedUntil.Valid {
+			retryAfter = max(retryAfter, throttle.LockedUntil.Time.Sub(now))
+		}
+	}
+	return retryAfter, nil
+}
+
+// clearAccountThrottle forgets an account's failed logins once a login has
+// gone all the way through, second factor included. The IP counter is left
+// alone: one good login must not wipe out the failures an attacker racked up
+// against other accounts.
+func (cfg *apiConfig) clearAccountThrottle(ctx context.Context, email string) {
+	if err := cfg.db.ClearLoginThrottle(ctx, accountThrottleKey(email)); err != nil {
+		log.Printf("Couldn't clear login throttle: %s", err)
+	}
+}
+
+// recordLoginFailure counts a failed attempt against key and locks it once the
+// policy says so. Errors are only logged: failing to count must not turn a
+// wrong password into a different response.
+func (cfg *apiConfig) recordLoginFailure(ctx context.Context, key string, policy throttlePolicy) {
+	now := time.Now().UTC()
+	throttle, err := cfg.db.RecordLoginFailure(ctx, database.RecordLoginFailureParams{
+		Key:         key,
+		WindowStart: now.Add(-policy.window),
+	})
+	if err != nil {
+		log.Printf("Couldn't record failed login for %s: %s", key, err)
+		return
+	}
+	lockout := policy.lockout(throttle.Failures)
+	if lockout == 0 {
+		return
+	}
+	err = cfg.db.LockLogin(ctx, database.LockLoginParams{
+		Key:         key,
+		LockedUntil: sql.NullTime{Time: now.Add(lockout), Valid: true},
+	})
+	if err != nil {
+		log.Printf("Couldn't lock login for %s: %s", key, err)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThrottlePolicyLockout(t *testing.T) {
+	policy := throttlePolicy{
+		freeAttempts: 3,
+		baseDelay:    time.Second,
+		maxDelay:     10 * time.Second,
+	}
+
+	tests := []struct {
+		failures int32
+		want     time.Duration
+	}{
+		{1, 0},
+		{2, 0},
+		{3, 0},
+		{4, time.Second},
+		{5, 2 * time.Second},
+		{6, 4 * time.Second},
+		{7, 8 * time.Second},
+		{8, 10 * time.Second},
+		{100, 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := policy.lockout(tt.failures); got != tt.want {
+			t.Errorf("After %d failures: expected %v, got %v", tt.failures, tt.want, got)
+		}
+	}
+}
+
+func TestThrottlePolicyLockout_Boundary(t *testing.T) {
+	for name, policy := range map[string]throttlePolicy{
+		"account": accountThrottle,
+		"ip":      ipThrottle,
+	} {
+		if got := policy.lockout(policy.freeAttempts); got != 0 {
+			t.Errorf("%s: expected the last free attempt not to lock, got %v", name, got)
+		}
+		if got := policy.lockout(policy.freeAttempts + 1); got != policy.baseDelay {
+			t.Errorf("%s: expected the first failure after the free attempts to lock for %v, got %v", name, policy.baseDelay, got)
+		}
+	}
+}
+
+func TestAccountThrottleKey(t *testing.T) {
+	want := accountThrottleKey("saul@bücher.example")
+	for _, email := range []string{"Saul@BÜCHER.example", " saul@xn--bcher-kva.example"} {
+		if got := accountThrottleKey(email); got != want {
+			t.Errorf("Expected %q to share the key %q, got %q", email, want, got)
+		}
+	}
+}
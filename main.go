@@ -1,23 +1,43 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"sync/atomic"
+	"time"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
+	"github.com/mvusic07/Chirpy/internal/auth"
 	"github.com/mvusic07/Chirpy/internal/database"
+	"github.com/mvusic07/Chirpy/internal/mailer"
 )
 
 type apiConfig struct {
 	fileserverHits atomic.Int32
+	conn           *sql.DB
 	db             *database.Queries
 	platform       string
-	tokenSecret    string
+	keys           *auth.KeySet
+	revocations    *revocationStore
+	mailer         mailer.Mailer
+	exporter       *dataExporter
+	passwords      auth.PasswordHasher
+	passwordPolicy auth.PasswordPolicy
+	baseURL        string
+
+	requireVerifiedEmail bool
+	polkaKey             string
+	polkaWebhookSecret   string
+	accountDeletionGrace time.Duration
+
+	timelinePrecomputeThreshold int
 }
 
 func main() {
@@ -29,14 +49,69 @@ func main() {
 	if dbURL == "" {
 		log.Fatal("DB_URL must be set")
 	}
+	if len(os.Args) > 1 {
+		if err := runCommand(context.Background(), dbURL, os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 	platform := os.Getenv("PLATFORM")
 	if platform == "" {
 		log.Fatal("PLATFORM must be set")
 	}
 
-	tokenSecret := os.Getenv("TOKEN_SECRET")
-	if tokenSecret == "" {
-		log.Fatal("TOKEN_SECRET must be set")
+	keyDir := os.Getenv("JWT_KEY_DIR")
+	if keyDir == "" {
+		keyDir = "keys"
+	}
+	keys, err := auth.LoadKeySet(keyDir)
+	if err != nil {
+		log.Fatalf("Error loading JWT signing keys: %s", err)
+	}
+	// TOKEN_SECRET is only needed while HS256 tokens issued before the move to
+	// asymmetric keys are still in circulation.
+	if tokenSecret := os.Getenv("TOKEN_SECRET"); tokenSecret != "" {
+		keys.AllowLegacySecret(tokenSecret)
+	}
+	var keyRotation time.Duration
+	if s := os.Getenv("JWT_KEY_ROTATION_INTERVAL"); s != "" {
+		keyRotation, err = time.ParseDuration(s)
+		if err != nil {
+			log.Fatalf("Invalid JWT_KEY_ROTATION_INTERVAL: %s", err)
+		}
+	}
+	go keys.Maintain(context.Background(), time.Minute, keyRotation, accessTokenTTL)
+
+	var deletionGrace time.Duration
+	if s := os.Getenv("ACCOUNT_DELETION_GRACE_PERIOD"); s != "" {
+		deletionGrace, err = time.ParseDuration(s)
+		if err != nil {
+			log.Fatalf("Invalid ACCOUNT_DELETION_GRACE_PERIOD: %s", err)
+		}
+	}
+
+	mail, err := newMailerFromEnv()
+	if err != nil {
+		log.Fatalf("Error configuring mailer: %s", err)
+	}
+	passwords, err := newPasswordHasherFromEnv()
+	if err != nil {
+		log.Fatalf("Error configuring password hashing: %s", err)
+	}
+	passwordPolicy, err := newPasswordPolicyFromEnv()
+	if err != nil {
+		log.Fatalf("Error configuring password policy: %s", err)
+	}
+	timelineThreshold := defaultTimelinePrecomputeThreshold
+	if s := os.Getenv("TIMELINE_PRECOMPUTE_THRESHOLD"); s != "" {
+		timelineThreshold, err = strconv.Atoi(s)
+		if err != nil || timelineThreshold < 1 {
+			log.Fatalf("Invalid TIMELINE_PRECOMPUTE_THRESHOLD: %q", s)
+		}
+	}
+	baseURL := os.Getenv("BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:" + port
 	}
 
 	dbConn, err := sql.Open("postgres", dbURL)
@@ -45,32 +120,40 @@ func main() {
 	}
 	dbQueries := database.New(dbConn)
 
+	revocations := newRevocationStore(dbQueries)
+	if err := revocations.Load(context.Background()); err != nil {
+		log.Fatalf("Error loading access token revocations: %s", err)
+	}
+	keys.SetRevocationChecker(revocations)
+	go revocations.Sync(context.Background(), 10*time.Second)
+
+	exporter := newDataExporter(dbQueries)
+	go exporter.Run(context.Background(), time.Minute)
+	go purgeDeletedAccounts(context.Background(), dbQueries, time.Hour)
+
 	apiCfg := apiConfig{
 		fileserverHits: atomic.Int32{},
+		conn:           dbConn,
 		db:             dbQueries,
 		platform:       platform,
-		tokenSecret:    tokenSecret,
+		keys:           keys,
+		revocations:    revocations,
+		mailer:         mail,
+		exporter:       exporter,
+		passwords:      passwords,
+		passwordPolicy: passwordPolicy,
+		baseURL:        strings.TrimSuffix(baseURL, "/"),
+
+		requireVerifiedEmail: os.Getenv("REQUIRE_VERIFIED_EMAIL") == "true",
+		polkaKey:             os.Getenv("POLKA_KEY"),
+		polkaWebhookSecret:   os.Getenv("POLKA_WEBHOOK_SECRET"),
+		accountDeletionGrace: deletionGrace,
+
+		timelinePrecomputeThreshold: timelineThreshold,
 	}
 
 	mux := http.NewServeMux()
-	fsHandler := apiCfg.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir(filepathRoot))))
-	mux.Handle("/app/", fsHandler)
-
-	mux.HandleFunc("GET /api/healthz", handlerReadiness)
-
-	mux.HandleFunc("POST /api/users", apiCfg.handlerUsersCreate)
-	mux.HandleFunc("PUT /api/users", apiCfg.handlerUpdate)
-	mux.HandleFunc("POST /api/login", apiCfg.handlerLogin)
-	mux.HandleFunc("POST /api/revoke", apiCfg.handlerRevoke)
-	mux.HandleFunc("POST /api/refresh", apiCfg.handlerRefresh)
-
-	mux.HandleFunc("POST /api/chirps", apiCfg.handlerChirpsCreate)
-	mux.HandleFunc("GET /api/chirps", apiCfg.handlerChirpsRetrieve)
-	mux.HandleFunc("GET /api/chirps/{chirpId}", apiCfg.handlerChirpsRetrieveById)
-	mux.HandleFunc("DELETE /api/chirps/{chirpId}", apiCfg.handlerChirpsDeleteById)
-
-	mux.HandleFunc("POST /admin/reset", apiCfg.handlerReset)
-	mux.HandleFunc("GET /admin/metrics", apiCfg.handlerMetrics)
+	apiCfg.registerRoutes(mux, filepathRoot)
 
 	srv := &http.Server{
 		Addr:    ":" + port,
@@ -80,3 +163,82 @@ func main() {
 	log.Printf("Serving on port: %s\n", port)
 	log.Fatal(srv.ListenAndServe())
 }
+
+// registerRoutes adds every route to mux. Patterns that overlap without one
+// being more specific make ServeMux panic, which TestRegisterRoutes catches.
+func (cfg *apiConfig) registerRoutes(mux *http.ServeMux, filepathRoot string) {
+	fsHandler := cfg.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir(filepathRoot))))
+	mux.Handle("/app/", fsHandler)
+
+	mux.HandleFunc("GET /api/healthz", handlerReadiness)
+	mux.HandleFunc("GET /.well-known/jwks.json", cfg.handlerJWKS)
+
+	// Every API route declares what it requires of the caller; see withAuth.
+	mux.Handle("POST /api/users", cfg.withAuth(authForbidden, cfg.handlerUsersCreate))
+	mux.Handle("PUT /api/users", cfg.withAuth(authSession, cfg.handlerUpdate))
+	mux.Handle("DELETE /api/users", cfg.withAuth(authSession, cfg.handlerUsersDelete))
+	mux.Handle("POST /api/users/export", cfg.withAuth(authSession, cfg.handlerExportsCreate))
+	mux.Handle("GET /api/exports/{exportId}", cfg.withAuth(authSession, cfg.handlerExportsGet))
+	mux.Handle("GET /api/exports/{exportId}/download", cfg.withAuth(authSession, cfg.handlerExportsDownload))
+	mux.Handle("POST /api/login", cfg.withAuth(authForbidden, cfg.handlerLogin))
+	mux.Handle("POST /api/login/mfa", cfg.withAuth(authForbidden, cfg.handlerLoginMFA))
+	mux.Handle("POST /api/mfa/totp/enroll", cfg.withAuth(authSession, cfg.handlerTOTPEnroll))
+	mux.Handle("POST /api/mfa/totp/confirm", cfg.withAuth(authSession, cfg.handlerTOTPConfirm))
+	mux.Handle("DELETE /api/mfa/totp", cfg.withAuth(authSession, cfg.handlerTOTPDisable))
+	mux.Handle("POST /api/password-reset/request", cfg.withAuth(authForbidden, cfg.handlerPasswordResetRequest))
+	mux.Handle("POST /api/password-reset/confirm", cfg.withAuth(authForbidden, cfg.handlerPasswordResetConfirm))
+	mux.Handle("GET /api/verify-email", cfg.withAuth(authForbidden, cfg.handlerVerifyEmail))
+
+	mux.Handle("GET /api/sessions", cfg.withAuth(authSession, cfg.handlerSessionsList))
+	mux.Handle("PATCH /api/sessions/{sessionId}", cfg.withAuth(authSession, cfg.handlerSessionsRename))
+	mux.Handle("DELETE /api/sessions/{sessionId}", cfg.withAuth(authSession, cfg.handlerSessionsRevoke))
+	mux.Handle("POST /api/sessions/revoke-all", cfg.withAuth(authSession, cfg.handlerSessionsRevokeAll))
+
+	mux.Handle("POST /api/tokens", cfg.withAuth(authSession, cfg.handlerTokensCreate))
+	mux.Handle("GET /api/tokens", cfg.withAuth(authSession, cfg.handlerTokensList))
+	mux.Handle("DELETE /api/tokens/{tokenId}", cfg.withAuth(authSession, cfg.handlerTokensRevoke))
+
+	mux.Handle("POST /api/oauth/clients", cfg.withAuth(authSession, cfg.handlerOAuthClientsCreate))
+	mux.Handle("GET /api/oauth/clients", cfg.withAuth(authSession, cfg.handlerOAuthClientsList))
+	mux.Handle("DELETE /api/oauth/clients/{clientId}", cfg.withAuth(authSession, cfg.handlerOAuthClientsDelete))
+
+	mux.Handle("GET /api/users/{handle}", cfg.withAuth(authOptional, cfg.handlerProfileGet))
+	mux.Handle("PATCH /api/me", cfg.withAuth(authRequired(scopeProfileWrite), cfg.handlerProfileUpdate))
+	mux.Handle("POST /api/users/{userId}/follow", cfg.withAuth(authRequired(scopeFollowsWrite), cfg.handlerFollow))
+	mux.Handle("DELETE /api/users/{userId}/follow", cfg.withAuth(authRequired(scopeFollowsWrite), cfg.handlerUnfollow))
+	mux.Handle("GET /api/users/{userId}/followers", cfg.withAuth(authOptional, cfg.handlerFollowersList))
+	mux.Handle("GET /api/users/{userId}/following", cfg.withAuth(authOptional, cfg.handlerFollowingList))
+	mux.Handle("GET /api/users/{userId}/likes", cfg.withAuth(authOptional, cfg.handlerUserLikesList))
+	mux.Handle("GET /api/timeline", cfg.withAuth(authRequired(scopeChirpsRead), cfg.handlerTimeline))
+	mux.Handle("GET /api/me/entitlements", cfg.withAuth(authRequired(scopeChirpsRead), cfg.handlerEntitlements))
+
+	mux.Handle("POST /api/chirps", cfg.withAuth(authRequired(scopeChirpsWrite), cfg.handlerChirpsCreate))
+	mux.Handle("GET /api/chirps", cfg.withAuth(authOptional, cfg.handlerChirpsRetrieve))
+	mux.Handle("GET /api/chirps/{chirpId}", cfg.withAuth(authOptional, cfg.handlerChirpsRetrieveById))
+	mux.Handle("GET /api/chirps/{chirpId}/thread", cfg.withAuth(authOptional, cfg.handlerChirpsThread))
+	mux.Handle("DELETE /api/chirps/{chirpId}", cfg.withAuth(authRequired(scopeChirpsWrite), cfg.handlerChirpsDeleteById))
+	mux.Handle("POST /api/chirps/{chirpId}/like", cfg.withAuth(authRequired(scopeLikesWrite), cfg.handlerLike))
+	mux.Handle("DELETE /api/chirps/{chirpId}/like", cfg.withAuth(authRequired(scopeLikesWrite), cfg.handlerUnlike))
+	mux.Handle("GET /api/chirps/{chirpId}/likes", cfg.withAuth(authOptional, cfg.handlerChirpLikesList))
+	mux.Handle("POST /api/chirps/{chirpId}/rechirp", cfg.withAuth(authRequired(scopeChirpsWrite), cfg.handlerRechirp))
+	mux.Handle("DELETE /api/chirps/{chirpId}/rechirp", cfg.withAuth(authRequired(scopeChirpsWrite), cfg.handlerUnrechirp))
+
+	mux.Handle("POST /admin/reset", cfg.withAuth(authPermission(permResetData), cfg.handlerReset))
+	mux.Handle("GET /admin/metrics", cfg.withAuth(authPermission(permReadMetrics), cfg.handlerMetrics))
+	mux.Handle("POST /admin/users/{userId}/unlock", cfg.withAuth(authPermission(permModerateUsers), cfg.handlerAdminUnlock))
+	mux.Handle("POST /admin/users/{userId}/suspend", cfg.withAuth(authPermission(permModerateUsers), cfg.handlerAdminSuspend))
+	mux.Handle("DELETE /admin/users/{userId}/suspend", cfg.withAuth(authPermission(permModerateUsers), cfg.handlerAdminUnsuspend))
+	mux.Handle("PUT /admin/users/{userId}/role", cfg.withAuth(authPermission(permManageUsers), cfg.handlerAdminSetRole))
+
+	// These routes take other credentials in the Authorization header (a
+	// refresh token, OAuth client credentials or a webhook API key) and check
+	// them themselves.
+	mux.HandleFunc("POST /api/revoke", cfg.handlerRevoke)
+	mux.HandleFunc("POST /api/refresh", cfg.handlerRefresh)
+	mux.HandleFunc("GET /oauth/authorize", cfg.handlerOAuthAuthorize)
+	mux.HandleFunc("POST /oauth/authorize", cfg.handlerOAuthAuthorizeDecision)
+	mux.HandleFunc("POST /oauth/token", cfg.handlerOAuthToken)
+	mux.HandleFunc("POST /oauth/revoke", cfg.handlerOAuthRevoke)
+	mux.HandleFunc("POST /oauth/introspect", cfg.handlerOAuthIntrospect)
+	mux.HandleFunc("POST /api/polka/webhooks", cfg.handlerPolkaWebhooks)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mvusic07/Chirpy/internal/database"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 100
+)
+
+// pageCursor is the keyset position of the last row on a page. Rows are
+// ordered by (created_at, id), so the pair is unique even when timestamps tie.
+// Binding ties the cursor to the query that produced it; see pageBinding.
+type pageCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+	Binding   string
+}
+
+type pageParams struct {
+	Desc   bool
+	Limit  int32
+	Cursor pageCursor
+	// Binding identifies the sort order and filters of the query, and is
+	// carried by the cursors of its pages.
+	Binding string
+}
+
+// pageBinding digests everything in query that selects or orders rows: the
+// sort order and any filters, such as author_id. The page size, the cursor
+// itself and ?expand= don't change which rows come next.
+func pageBinding(query url.Values, desc bool) string {
+	query = maps.Clone(query)
+	for _, key := range []string{"cursor", "limit", "expand"} {
+		query.Del(key)
+	}
+	query.Set("sort", "asc")
+	if desc {
+		query.Set("sort", "desc")
+	}
+	sum := sha256.Sum256([]byte(query.Encode()))
+	return hex.EncodeToString(sum[:8])
+}
+
+// firstPageCursor sits before the first row in the requested direction so the
+// first page can use the same index range scan as every other page.
+func firstPageCursor(desc bool) pageCursor {
+	if desc {
+		return pageCursor{
+			CreatedAt: time.Date(9999, time.December, 31, 23, 59, 59, 0, time.UTC),
+			ID:        uuid.Max,
+		}
+	}
+	return pageCursor{
+		CreatedAt: time.Time{},
+		ID:        uuid.Nil,
+	}
+}
+
+func encodeCursor(c pageCursor) string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String() + "|" + c.Binding
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string) (pageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return pageCursor{}, errors.New("malformed cursor")
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 {
+		return pageCursor{}, errors.New("malformed cursor")
+	}
+	createdAt, id, binding := parts[0], parts[1], parts[2]
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return pageCursor{}, errors.New("malformed cursor")
+	}
+	u, err := uuid.Parse(id)
+	if err != nil {
+		return pageCursor{}, errors.New("malformed cursor")
+	}
+	return pageCursor{CreatedAt: t, ID: u, Binding: binding}, nil
+}
+
+// parsePageParams reads ?sort=, ?limit= and ?cursor= from the query string.
+// A cursor is only accepted with the sort order and filters it was issued for;
+// anywhere else it would silently skip or repeat rows.
+func parsePageParams(query url.Values) (pageParams, error) {
+	params := pageParams{Limit: defaultPageLimit}
+
+	switch query.Get("sort") {
+	case "", "asc":
+	case "desc":
+		params.Desc = true
+	default:
+		return pageParams{}, errors.New("sort must be asc or desc")
+	}
+
+	if s := query.Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return pageParams{}, fmt.Errorf("limit must be between 1 and %d", maxPageLimit)
+		}
+		params.Limit = int32(limit)
+	}
+
+	params.Binding = pageBinding(query, params.Desc)
+	params.Cursor = firstPageCursor(params.Desc)
+	if s := query.Get("cursor"); s != "" {
+		cursor, err := decodeCursor(s)
+		if err != nil {
+			return pageParams{}, err
+		}
+		if cursor.Binding != params.Binding {
+			return pageParams{}, errors.New("cursor doesn't match the sort order or filters of this request")
+		}
+		params.Cursor = cursor
+	}
+
+	return params, nil
+}
+
+// parseFeedPageParams is parsePageParams for lists that are always newest
+// first, such as timelines.
+func parseFeedPageParams(query url.Values) (pageParams, error) {
+	switch query.Get("sort") {
+	case "", "desc":
+	default:
+		return pageParams{}, errors.New("sort must be desc")
+	}
+	query = maps.Clone(query)
+	query.Set("sort", "desc")
+	return parsePageParams(query)
+}
+
+// parseThreadPageParams is parsePageParams for replies, which read oldest
+// first like a conversation.
+func parseThreadPageParams(query url.Values) (pageParams, error) {
+	switch query.Get("sort") {
+	case "", "asc":
+	default:
+		return pageParams{}, errors.New("sort must be asc")
+	}
+	return parsePageParams(query)
+}
+
+// fetchLimit is how many rows to ask for: one more than the page holds, so
+// that trimPage can tell whether a next page exists.
+func (p pageParams) fetchLimit() int32 {
+	return p.Limit + 1
+}
+
+// trimPage cuts rows fetched with page.fetchLimit down to the page and, if
+// there were more, links to the next page, which starts after the last row
+// kept.
+func trimPage[T any](w http.ResponseWriter, r *http.Request, page pageParams, rows []T, cursorOf func(T) pageCursor) []T {
+	if len(rows) <= int(page.Limit) {
+		return rows
+	}
+	rows = rows[:page.Limit]
+	setNextLink(w, r, page, cursorOf(rows[len(rows)-1]))
+	return rows
+}
+
+// chirpCursor is the cursor for lists of chirps ordered by creation time.
+func chirpCursor(c database.Chirp) pageCursor {
+	return pageCursor{CreatedAt: c.CreatedAt, ID: c.ID}
+}
+
+// setNextLink advertises the next page through an RFC 8288 Link header,
+// keeping every other query parameter of the current request.
+func setNextLink(w http.ResponseWriter, r *http.Request, page pageParams, next pageCursor) {
+	next.Binding = page.Binding
+	query := r.URL.Query()
+	query.Set("cursor", encodeCursor(next))
+	nextURL := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, nextURL.String()))
+}
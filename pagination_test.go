@@ -0,0 +1,188 @@
+package main
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mvusic07/Chirpy/internal/database"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	want := pageCursor{
+		CreatedAt: time.Date(2025, time.March, 4, 10, 11, 12, 123456000, time.UTC),
+		ID:        uuid.New(),
+		Binding:   pageBinding(url.Values{"author_id": {uuid.NewString()}}, true),
+	}
+
+	got, err := decodeCursor(encodeCursor(want))
+	if err != nil {
+		t.Fatalf("decodeCursor failed: %v", err)
+	}
+	if !got.CreatedAt.Equal(want.CreatedAt) || got.ID != want.ID || got.Binding != want.Binding {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	for _, s := range []string{"", "!!!", "bm9waXBl", encodeCursor(pageCursor{})[:10]} {
+		if _, err := decodeCursor(s); err == nil {
+			t.Errorf("Expected error for cursor %q", s)
+		}
+	}
+}
+
+func TestParsePageParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    pageParams
+		wantErr bool
+	}{
+		{
+			name:  "defaults",
+			query: "",
+			want:  pageParams{Limit: defaultPageLimit, Cursor: firstPageCursor(false), Binding: pageBinding(url.Values{}, false)},
+		},
+		{
+			name:  "desc with limit",
+			query: "sort=desc&limit=10",
+			want:  pageParams{Desc: true, Limit: 10, Cursor: firstPageCursor(true), Binding: pageBinding(url.Values{}, true)},
+		},
+		{name: "bad sort", query: "sort=sideways", wantErr: true},
+		{name: "zero limit", query: "limit=0", wantErr: true},
+		{name: "limit too large", query: "limit=1000", wantErr: true},
+		{name: "bad cursor", query: "cursor=nope", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, _ := url.ParseQuery(tt.query)
+			got, err := parsePageParams(query)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePageParams failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParsePageParams_CursorBinding(t *testing.T) {
+	authorID := uuid.NewString()
+	first, err := parsePageParams(url.Values{"sort": {"desc"}, "author_id": {authorID}})
+	if err != nil {
+		t.Fatalf("parsePageParams failed: %v", err)
+	}
+	next := pageCursor{CreatedAt: time.Now().UTC(), ID: uuid.New(), Binding: first.Binding}
+	cursor := encodeCursor(next)
+
+	tests := []struct {
+		name    string
+		query   url.Values
+		wantErr bool
+	}{
+		{"same query", url.Values{"sort": {"desc"}, "author_id": {authorID}}, false},
+		{"different limit and expand", url.Values{"sort": {"desc"}, "author_id": {authorID}, "limit": {"5"}, "expand": {"author"}}, false},
+		{"different sort", url.Values{"sort": {"asc"}, "author_id": {authorID}}, true},
+		{"default sort", url.Values{"author_id": {authorID}}, true},
+		{"different author", url.Values{"sort": {"desc"}, "author_id": {uuid.NewString()}}, true},
+		{"no author", url.Values{"sort": {"desc"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.query.Set("cursor", cursor)
+			page, err := parsePageParams(tt.query)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected the cursor to be rejected")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePageParams failed: %v", err)
+			}
+			if page.Cursor.ID != next.ID {
+				t.Errorf("Expected to resume after %s, got %+v", next.ID, page.Cursor)
+			}
+		})
+	}
+}
+
+func TestParseFeedPageParams(t *testing.T) {
+	page, err := parseFeedPageParams(url.Values{})
+	if err != nil {
+		t.Fatalf("parseFeedPageParams failed: %v", err)
+	}
+	if !page.Desc || page.Cursor != firstPageCursor(true) {
+		t.Errorf("Expected newest first by default, got %+v", page)
+	}
+
+	query := url.Values{"sort": {"asc"}}
+	if _, err := parseFeedPageParams(query); err == nil {
+		t.Error("Expected sort=asc to be rejected")
+	}
+	if query.Get("sort") != "asc" {
+		t.Error("Expected the query not to be modified")
+	}
+}
+
+func TestParseThreadPageParams(t *testing.T) {
+	page, err := parseThreadPageParams(url.Values{})
+	if err != nil {
+		t.Fatalf("parseThreadPageParams failed: %v", err)
+	}
+	if page.Desc || page.Cursor != firstPageCursor(false) {
+		t.Errorf("Expected oldest first by default, got %+v", page)
+	}
+	if _, err := parseThreadPageParams(url.Values{"sort": {"desc"}}); err == nil {
+		t.Error("Expected sort=desc to be rejected")
+	}
+}
+
+func TestTrimPage(t *testing.T) {
+	page, err := parsePageParams(url.Values{"limit": {"2"}})
+	if err != nil {
+		t.Fatalf("parsePageParams failed: %v", err)
+	}
+	rows := []database.Chirp{
+		{ID: uuid.New(), CreatedAt: time.Unix(1, 0)},
+		{ID: uuid.New(), CreatedAt: time.Unix(2, 0)},
+		{ID: uuid.New(), CreatedAt: time.Unix(3, 0)},
+	}
+
+	// A full page plus the extra row: trimmed, with a link after the last kept.
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/chirps?limit=2", nil)
+	got := trimPage(rec, req, page, rows[:page.fetchLimit()], chirpCursor)
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 rows, got %d", len(got))
+	}
+	link := rec.Header().Get("Link")
+	next := url.Values{"limit": {"2"}, "cursor": {encodeCursor(pageCursor{
+		CreatedAt: rows[1].CreatedAt,
+		ID:        rows[1].ID,
+		Binding:   page.Binding,
+	})}}
+	if want := `</api/chirps?` + next.Encode() + `>; rel="next"`; link != want {
+		t.Errorf("Expected Link %s, got %s", want, link)
+	}
+
+	// The last page: left alone, without a link.
+	rec = httptest.NewRecorder()
+	if got := trimPage(rec, req, page, rows[:2], chirpCursor); len(got) != 2 {
+		t.Errorf("Expected 2 rows, got %d", len(got))
+	}
+	if link := rec.Header().Get("Link"); link != "" {
+		t.Errorf("Expected no Link on the last page, got %s", link)
+	}
+}
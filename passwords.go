@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/mvusic07/Chirpy/internal/auth"
+	"github.com/mvusic07/Chirpy/internal/database"
+)
+
+// newPasswordHasherFromEnv builds the argon2id hasher, letting
+// ARGON2_MEMORY (KiB), ARGON2_ITERATIONS and ARGON2_PARALLELISM override the
+// defaults. Existing hashes are upgraded to the new parameters as their
+// owners log in.
+func newPasswordHasherFromEnv() (auth.PasswordHasher, error) {
+	params := auth.DefaultArgon2idParams
+	for _, setting := range []struct {
+		env  string
+		bits int
+		set  func(uint64)
+	}{
+		{"ARGON2_MEMORY", 32, func(v uint64) { params.Memory = uint32(v) }},
+		{"ARGON2_ITERATIONS", 32, func(v uint64) { params.Iterations = uint32(v) }},
+		{"ARGON2_PARALLELISM", 8, func(v uint64) { params.Parallelism = uint8(v) }},
+	} {
+		s := os.Getenv(setting.env)
+		if s == "" {
+			continue
+		}
+		v, err := strconv.ParseUint(s, 10, setting.bits)
+		if err != nil || v == 0 {
+			return nil, fmt.Errorf("invalid %s %q", setting.env, s)
+		}
+		setting.set(v)
+	}
+	return auth.NewArgon2idHasher(params), nil
+}
+
+// rehashPassword replaces a user's password hash with one made using the
+// current algorithm and parameters. It is called after a successful login,
+// the only time the plaintext is available; failures are logged and retried
+// on the next login.
+func (cfg *apiConfig) rehashPassword(ctx context.Context, userID uuid.UUID, password string) {
+	hash, err := cfg.passwords.Hash(password)
+	if err != nil {
+		log.Printf("Couldn't rehash password: %s", err)
+		return
+	}
+	_, err = cfg.db.UpdateUserPassword(ctx, database.UpdateUserPasswordParams{
+		ID:             userID,
+		HashedPassword: hash,
+	})
+	if err != nil {
+		log.Printf("Couldn't store rehashed password: %s", err)
+	}
+}
+
+// newPasswordPolicyFromEnv builds the policy for new passwords.
+// PASSWORD_MIN_LENGTH and PASSWORD_MIN_STRENGTH (0-4) override the defaults,
+// and BREACHED_PASSWORDS_FILE names a sorted file of SHA-1 hashes, such as
+// the Have I Been Pwned download, to reject known breached passwords.
+func newPasswordPolicyFromEnv() (auth.PasswordPolicy, error) {
+	policy := auth.DefaultPasswordPolicy
+	if s := os.Getenv("PASSWORD_MIN_LENGTH"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return policy, fmt.Errorf("invalid PASSWORD_MIN_LENGTH %q", s)
+		}
+		policy.MinLength = n
+	}
+	if s := os.Getenv("PASSWORD_MIN_STRENGTH"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 || n > 4 {
+			return policy, fmt.Errorf("invalid PASSWORD_MIN_STRENGTH %q", s)
+		}
+		policy.MinStrength = n
+	}
+	if path := os.Getenv("BREACHED_PASSWORDS_FILE"); path != "" {
+		corpus, err := auth.OpenBreachedPasswordFile(path)
+		if err != nil {
+			return policy, fmt.Errorf("loading breached passwords: %w", err)
+		}
+		policy.Breached = corpus
+	}
+	return policy, nil
+}
+
+// checkNewPassword validates password against the policy for an account with
+// the given emails. If it falls short, it responds with the reasons and
+// returns false.
+func (cfg *apiConfig) checkNewPassword(w http.ResponseWriter, password string, emails ...string) bool {
+	violations, err := cfg.passwordPolicy.Check(password, emails...)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't check password", err)
+		return false
+	}
+	if len(violations) == 0 {
+		return true
+	}
+	fields := make([]fieldError, len(violations))
+	for i, v := range violations {
+		fields[i] = fieldError{Field: "password", Code: v.Code, Message: v.Message}
+	}
+	respondWithValidationErrors(w, "Password doesn't meet the requirements", fields)
+	return false
+}
+
+// confirmPassword checks that password is the user's before a sensitive
+// change, responding with an error and returning false if not. A stolen
+// access token mustn't be enough to guess the password, so wrong guesses
+// count towards the login lockout.
+func (cfg *apiConfig) confirmPassword(w http.ResponseWriter, r *http.Request, user database.User, password string) bool {
+	accountKey := accountThrottleKey(user.Email)
+	retryAfter, err := cfg.loginRetryAfter(r.Context(), accountKey)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't check login attempts", err)
+		return false
+	}
+	if retryAfter > 0 {
+		respondWithRetryAfter(w, retryAfter)
+		return false
+	}
+	if _, err := cfg.passwords.Verify(password, user.HashedPassword); err != nil {
+		cfg.recordLoginFailure(r.Context(), accountKey, accountThrottle)
+		respondWithError(w, http.StatusUnauthorized, "Incorrect password", err)
+		return false
+	}
+	return true
+}
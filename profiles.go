@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/mvusic07/Chirpy/internal/auth"
+	"github.com/mvusic07/Chirpy/internal/database"
+)
+
+// Profile is the public view of a user. It must never include the email.
+type Profile struct {
+	ID          uuid.UUID `json:"id"`
+	Handle      string    `json:"handle"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	AvatarURL   string    `json:"avatar_url"`
+	Location    string    `json:"location"`
+	CreatedAt   time.Time `json:"created_at"`
+	IsChirpyRed bool      `json:"is_chirpy_red"`
+}
+
+func newProfile(u database.User) Profile {
+	return Profile{
+		ID:          u.ID,
+		Handle:      u.Handle,
+		DisplayName: u.DisplayName,
+		Bio:         u.Bio,
+		AvatarURL:   u.AvatarUrl,
+		Location:    u.Location,
+		CreatedAt:   u.CreatedAt,
+		IsChirpyRed: isChirpyRed(u),
+	}
+}
+
+// AuthorSummary is the part of a profile embedded in chirps.
+type AuthorSummary struct {
+	ID          uuid.UUID `json:"id"`
+	Handle      string    `json:"handle"`
+	DisplayName string    `json:"display_name"`
+	AvatarURL   string    `json:"avatar_url"`
+	IsChirpyRed bool      `json:"is_chirpy_red"`
+}
+
+// attachAuthors embeds the author summary of each chirp, looking every author
+// up once. Tombstones left by deleted accounts have no author to embed.
+func (cfg *apiConfig) attachAuthors(ctx context.Context, chirps []Chirp) error {
+	ids := []uuid.UUID{}
+	for _, chirp := range chirps {
+		if chirp.UserID != nil && !slices.Contains(ids, *chirp.UserID) {
+			ids = append(ids, *chirp.UserID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	rows, err := cfg.db.GetAuthorSummaries(ctx, ids)
+	if err != nil {
+		return err
+	}
+	authors := make(map[uuid.UUID]*AuthorSummary, len(rows))
+	for _, row := range rows {
+		authors[row.ID] = &AuthorSummary{
+			ID:          row.ID,
+			Handle:      row.Handle,
+			DisplayName: row.DisplayName,
+			AvatarURL:   row.AvatarUrl,
+			IsChirpyRed: chirpyRedActive(row.IsChirpyRed, row.ChirpyRedUntil),
+		}
+	}
+	for i := range chirps {
+		if chirps[i].UserID != nil {
+			chirps[i].Author = authors[*chirps[i].UserID]
+		}
+	}
+	return nil
+}
+
+// wantsExpansion reports whether the request asked for field to be embedded,
+// as in ?expand=author.
+func wantsExpansion(values url.Values, field string) bool {
+	for _, expand := range values["expand"] {
+		if slices.Contains(strings.Split(expand, ","), field) {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 160
+	maxLocationLength    = 30
+	maxAvatarURLLength   = 2048
+)
+
+var handlePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,15}$`)
+
+// reservedHandles can't be taken, because they would be confused with the
+// service itself or shadow a route under /api/users.
+var reservedHandles = []string{"admin", "api", "chirpy", "export", "me", "moderator", "root", "support"}
+
+var (
+	errHandleFormat   = errors.New("Handle must be 3 to 15 letters, digits or underscores")
+	errHandleReserved = errors.New("Handle is reserved")
+)
+
+// validateHandle checks a handle as typed, without the leading @.
+func validateHandle(handle string) error {
+	if !handlePattern.MatchString(handle) {
+		return errHandleFormat
+	}
+	if slices.Contains(reservedHandles, strings.ToLower(handle)) {
+		return errHandleReserved
+	}
+	return nil
+}
+
+// generateHandle makes a placeholder handle for accounts created without one.
+func generateHandle() string {
+	return "user_" + auth.MakeToken()[:10]
+}
+
+// validateAvatarURL accepts an https URL, or "" for no avatar.
+func validateAvatarURL(s string) error {
+	if s == "" {
+		return nil
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme != "https" || u.Host == "" || len(s) > maxAvatarURLLength {
+		return errors.New("Avatar URL must be an https:// link")
+	}
+	return nil
+}
+
+func tooLong(s string, max int) bool {
+	return utf8.RuneCountInString(s) > max
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestValidateHandle(t *testing.T) {
+	tests := []struct {
+		handle  string
+		wantErr error
+	}{
+		{handle: "walter_white"},
+		{handle: "Heisenberg"},
+		{handle: "abc"},
+		{handle: "ab", wantErr: errHandleFormat},
+		{handle: "this_is_too_long", wantErr: errHandleFormat},
+		{handle: "walter.white", wantErr: errHandleFormat},
+		{handle: "wältér", wantErr: errHandleFormat},
+		{handle: "", wantErr: errHandleFormat},
+		{handle: "Admin", wantErr: errHandleReserved},
+		{handle: "export", wantErr: errHandleReserved},
+	}
+	for _, tt := range tests {
+		t.Run(tt.handle, func(t *testing.T) {
+			if err := validateHandle(tt.handle); err != tt.wantErr {
+				t.Errorf("Expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	if err := validateHandle(generateHandle()); err != nil {
+		t.Errorf("Expected generated handles to be valid, got %v", err)
+	}
+}
+
+func TestValidateAvatarURL(t *testing.T) {
+	valid := []string{"", "https://cdn.example.com/walt.png"}
+	invalid := []string{"http://cdn.example.com/walt.png", "javascript:alert(1)", "https://", "/walt.png"}
+	for _, s := range valid {
+		if err := validateAvatarURL(s); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", s, err)
+		}
+	}
+	for _, s := range invalid {
+		if err := validateAvatarURL(s); err == nil {
+			t.Errorf("Expected %q to be rejected", s)
+		}
+	}
+}
+
+func TestWantsExpansion(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{query: "expand=author", want: true},
+		{query: "expand=replies,author", want: true},
+		{query: "expand=replies&expand=author", want: true},
+		{query: "expand=authors", want: false},
+		{query: "", want: false},
+	}
+	for _, tt := range tests {
+		values, _ := url.ParseQuery(tt.query)
+		if got := wantsExpansion(values, "author"); got != tt.want {
+			t.Errorf("wantsExpansion(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestProfileUpdate_Credentials(t *testing.T) {
+	s := newTestServer(t)
+	session := s.signup(t, "saul@example.com")
+
+	rec := s.do(t, "PATCH", "/api/me", session.Token, map[string]string{
+		"email":            "jimmy@example.com",
+		"current_password": "wrong",
+	})
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a wrong current password to be rejected, got %d: %s", rec.Code, rec.Body)
+	}
+
+	var user User
+	decode(t, s.do(t, "PATCH", "/api/me", session.Token, map[string]string{
+		"email":            "Jimmy@Example.com",
+		"bio":              "Lawyer",
+		"current_password": testPassword,
+	}), http.StatusOK, &user)
+	if user.Email != "saul@example.com" || user.PendingEmail != "jimmy@example.com" || user.Bio != "Lawyer" {
+		t.Errorf("Expected the new email to wait for confirmation, got %+v", user)
+	}
+
+	const newPassword = "tattered sweater lobby cactus"
+	decode(t, s.do(t, "PATCH", "/api/me", session.Token, map[string]string{
+		"password":         newPassword,
+		"current_password": testPassword,
+	}), http.StatusOK, nil)
+	decode(t, s.do(t, "POST", "/api/login", "", map[string]string{
+		"email":    "saul@example.com",
+		"password": newPassword,
+	}), http.StatusOK, nil)
+	if rec := s.do(t, "GET", "/api/sessions", session.Token, nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a password change to revoke existing sessions, got %d", rec.Code)
+	}
+}
+
+func TestProfileUpdate_CredentialsRequireSession(t *testing.T) {
+	s := newTestServer(t)
+	session := s.signup(t, "kim@example.com")
+	pat := s.personalAccessToken(t, session, scopeProfileWrite)
+
+	rec := s.do(t, "PATCH", "/api/me", pat, map[string]string{
+		"email":            "kim@example.org",
+		"current_password": testPassword,
+	})
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a personal access token to be refused, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestProfileUpdate_FailedEmailChangeSavesNothing(t *testing.T) {
+	s := newTestServer(t)
+	session := s.signup(t, "marco@example.com")
+	s.signup(t, "lalo@example.com")
+
+	rec := s.do(t, "PATCH", "/api/me", session.Token, map[string]string{
+		"bio":              "Pinky ring",
+		"email":            "lalo@example.com",
+		"password":         "tattered sweater lobby cactus",
+		"current_password": testPassword,
+	})
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("Expected the email to be in use, got %d: %s", rec.Code, rec.Body)
+	}
+
+	var bio string
+	if err := s.conn.QueryRow("SELECT bio FROM users WHERE id = $1", session.ID).Scan(&bio); err != nil {
+		t.Fatalf("Couldn't read bio: %v", err)
+	}
+	if bio != "" {
+		t.Errorf("Expected the bio to be left alone, got %q", bio)
+	}
+	// Nor was the password changed.
+	s.login(t, "marco@example.com")
+}
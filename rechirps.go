@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/mvusic07/Chirpy/internal/database"
+)
+
+// SharedChirp is the chirp a rechirp or quote refers to. When it can't be
+// shown, because it was deleted or its author's account is suspended or
+// closing, only its ID is given.
+type SharedChirp struct {
+	*Chirp
+	ID          uuid.UUID `json:"id"`
+	Unavailable bool      `json:"unavailable,omitempty"`
+}
+
+var errSharedNotFound = errors.New("shared chirp not found")
+
+// sharedID returns the ID of the chirp that c shares, if any.
+func sharedID(c Chirp) (uuid.UUID, bool) {
+	switch {
+	case c.RechirpOfID != nil:
+		return *c.RechirpOfID, true
+	case c.QuoteOfID != nil:
+		return *c.QuoteOfID, true
+	}
+	return uuid.Nil, false
+}
+
+// sharedChirps loads the chirps shared by chirps that can still be shown.
+// Each is loaded once, however many chirps share it.
+func (cfg *apiConfig) sharedChirps(ctx context.Context, chirps []Chirp) ([]Chirp, error) {
+	ids := []uuid.UUID{}
+	seen := map[uuid.UUID]bool{}
+	for _, chirp := range chirps {
+		if id, ok := sharedID(chirp); ok && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	rows, err := cfg.db.ListSharedChirps(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	shared := []Chirp{}
+	for _, row := range rows {
+		if row.AuthorHidden || row.Chirp.DeletedAt.Valid {
+			continue
+		}
+		shared = append(shared, newChirp(row.Chirp))
+	}
+	return shared, nil
+}
+
+// attachShared embeds in each rechirp or quote the chirp it shares, marking
+// it unavailable if it isn't among shared.
+func attachShared(chirps, shared []Chirp) {
+	byID := make(map[uuid.UUID]*Chirp, len(shared))
+	for i := range shared {
+		byID[shared[i].ID] = &shared[i]
+	}
+	for i := range chirps {
+		id, ok := sharedID(chirps[i])
+		if !ok {
+			continue
+		}
+		original := byID[id]
+		chirps[i].Original = &SharedChirp{
+			Chirp:       original,
+			ID:          id,
+			Unavailable: original == nil,
+		}
+	}
+}
+
+// shareTarget finds a chirp to rechirp or quote. Sharing a rechirp shares
+// the chirp it refers to, so shares are never more than one level deep.
+func (cfg *apiConfig) shareTarget(ctx context.Context, chirpID uuid.UUID) (database.Chirp, error) {
+	chirp, err := cfg.visibleChirp(ctx, chirpID)
+	if err == nil && chirp.RechirpOfID.Valid {
+		chirp, err = cfg.visibleChirp(ctx, chirp.RechirpOfID.UUID)
+	}
+	return chirp, err
+}
+
+// visibleChirp finds a chirp that isn't a tombstone and whose author's
+// account is in good standing.
+func (cfg *apiConfig) visibleChirp(ctx context.Context, chirpID uuid.UUID) (database.Chirp, error) {
+	rows, err := cfg.db.ListSharedChirps(ctx, []uuid.UUID{chirpID})
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	if len(rows) == 0 || rows[0].AuthorHidden || rows[0].Chirp.DeletedAt.Valid {
+		return database.Chirp{}, errSharedNotFound
+	}
+	return rows[0].Chirp, nil
+}
+
+// handlerRechirp shares a chirp with the caller's followers. Rechirping a
+// chirp again returns the existing rechirp. Rechirps don't count against the
+// posting budget: they add no content, and there can be only one per chirp.
+func (cfg *apiConfig) handlerRechirp(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpId"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+	user, _, ok := cfg.postingUser(w, r)
+	if !ok {
+		return
+	}
+	original, err := cfg.shareTarget(r.Context(), chirpID)
+	if errors.Is(err, errSharedNotFound) {
+		respondWithError(w, http.StatusNotFound, "Chirp with provided id doesn't exist", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't find chirp", err)
+		return
+	}
+
+	status := http.StatusCreated
+	rechirp, err := cfg.db.CreateChirp(r.Context(), database.CreateChirpParams{
+		UserID:      user.ID,
+		RechirpOfID: uuid.NullUUID{UUID: original.ID, Valid: true},
+	})
+	if isUniqueViolation(err, "chirps_rechirp_idx") {
+		status = http.StatusOK
+		rechirp, err = cfg.db.GetRechirp(r.Context(), database.GetRechirpParams{
+			UserID:      user.ID,
+			RechirpOfID: uuid.NullUUID{UUID: original.ID, Valid: true},
+		})
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't rechirp", err)
+		return
+	}
+	if status == http.StatusCreated {
+		cfg.chirpPosted(r.Context(), rechirp)
+	}
+
+	chirps := []Chirp{newChirp(rechirp)}
+	if err := cfg.decorateChirps(r, chirps); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirp", err)
+		return
+	}
+	respondWithJSON(w, status, chirps[0])
+}
+
+// undoTarget resolves the chirp named when taking back a rechirp or like:
+// a rechirp stands for the chirp it shares, as it does when rechirping or
+// liking. Nothing is checked for visibility, so that a chirp since deleted
+// or hidden can still be un-shared and un-liked; an unknown ID is returned
+// as is.
+func (cfg *apiConfig) undoTarget(ctx context.Context, chirpID uuid.UUID) (uuid.UUID, error) {
+	chirp, err := cfg.db.GetChirpById(ctx, chirpID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return chirpID, nil
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if chirp.RechirpOfID.Valid {
+		return chirp.RechirpOfID.UUID, nil
+	}
+	return chirpID, nil
+}
+
+// handlerUnrechirp removes the caller's rechirp of a chirp, if there is one.
+func (cfg *apiConfig) handlerUnrechirp(w http.ResponseWriter, r *http.Request) {
+	caller, _ := requestPrincipal(r)
+	chirpID, err := uuid.Parse(r.PathValue("chirpId"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid chirp ID", err)
+		return
+	}
+	chirpID, err = cfg.undoTarget(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't find chirp", err)
+		return
+	}
+	_, err = cfg.db.DeleteRechirp(r.Context(), database.DeleteRechirpParams{
+		UserID:      caller.UserID,
+		RechirpOfID: uuid.NullUUID{UUID: chirpID, Valid: true},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't remove rechirp", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
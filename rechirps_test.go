@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestAttachShared(t *testing.T) {
+	original := Chirp{ID: uuid.New(), Body: "original"}
+	goneID := uuid.New()
+	rechirp := Chirp{ID: uuid.New(), RechirpOfID: &original.ID}
+	quote := Chirp{ID: uuid.New(), Body: "look", QuoteOfID: &goneID}
+	plain := Chirp{ID: uuid.New(), Body: "plain"}
+	chirps := []Chirp{rechirp, quote, plain}
+
+	attachShared(chirps, []Chirp{original})
+
+	got := chirps[0].Original
+	if got == nil || got.Unavailable || got.Chirp == nil || got.Body != "original" {
+		t.Errorf("Expected the rechirp to embed the original, got %+v", got)
+	}
+	got = chirps[1].Original
+	if got == nil || !got.Unavailable || got.Chirp != nil || got.ID != goneID {
+		t.Errorf("Expected the quote to embed an unavailable chirp, got %+v", got)
+	}
+	if chirps[2].Original != nil {
+		t.Errorf("Expected nothing embedded in a plain chirp, got %+v", chirps[2].Original)
+	}
+}
+
+func TestSharedChirp_UnavailableJSON(t *testing.T) {
+	id := uuid.New()
+	data, err := json.Marshal(SharedChirp{ID: id, Unavailable: true})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if len(got) != 2 || got["id"] != id.String() || got["unavailable"] != true {
+		t.Errorf("Expected only id and unavailable, got %s", data)
+	}
+
+	data, err = json.Marshal(SharedChirp{Chirp: &Chirp{ID: id, Body: "hi"}, ID: id})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	got = nil
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if got["body"] != "hi" || got["id"] != id.String() || got["unavailable"] != nil {
+		t.Errorf("Expected the chirp's fields inline, got %s", data)
+	}
+}
+
+func TestRechirp_RoundTrip(t *testing.T) {
+	s := newTestServer(t)
+	author := s.signup(t, "jimmy@example.com")
+	sharer := s.signup(t, "chuck@example.com")
+	original := s.postChirp(t, author, map[string]any{"body": "share me"})
+	path := "/api/chirps/" + original.ID.String()
+
+	// A rechirp's id stands for the original, as it does when rechirping.
+	var rechirp Chirp
+	decode(t, s.do(t, "POST", path+"/rechirp", sharer.Token, nil), http.StatusCreated, &rechirp)
+	decode(t, s.do(t, "DELETE", "/api/chirps/"+rechirp.ID.String()+"/rechirp", sharer.Token, nil), http.StatusNoContent, nil)
+
+	var got Chirp
+	decode(t, s.do(t, "GET", path, "", nil), http.StatusOK, &got)
+	if got.RechirpCount != 0 {
+		t.Errorf("Expected rechirp_count 0 after undoing, got %d", got.RechirpCount)
+	}
+	if rec := s.do(t, "GET", "/api/chirps/"+rechirp.ID.String(), "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected the rechirp to be gone, got %d: %s", rec.Code, rec.Body)
+	}
+
+	// With the old one gone, rechirping again makes a new one.
+	decode(t, s.do(t, "POST", path+"/rechirp", sharer.Token, nil), http.StatusCreated, &rechirp)
+	decode(t, s.do(t, "DELETE", path+"/rechirp", sharer.Token, nil), http.StatusNoContent, nil)
+	decode(t, s.do(t, "GET", path, "", nil), http.StatusOK, &got)
+	if got.RechirpCount != 0 {
+		t.Errorf("Expected rechirp_count 0 after undoing by the original's id, got %d", got.RechirpCount)
+	}
+}
+
+func TestDeleteQuote_PrunesQuotedTombstone(t *testing.T) {
+	s := newTestServer(t)
+	author := s.signup(t, "ignacio@example.com")
+	quoter := s.signup(t, "manuel@example.com")
+	quoted := s.postChirp(t, author, map[string]any{"body": "quote me"})
+	quote := s.postChirp(t, quoter, map[string]any{"body": "quoting", "quote_of_id": quoted.ID})
+
+	// Still quoted, the chirp stays as a tombstone.
+	decode(t, s.do(t, "DELETE", "/api/chirps/"+quoted.ID.String(), author.Token, nil), http.StatusNoContent, nil)
+	var left int
+	if err := s.conn.QueryRow("SELECT COUNT(*) FROM chirps WHERE id = $1", quoted.ID).Scan(&left); err != nil {
+		t.Fatalf("Couldn't look for the tombstone: %v", err)
+	}
+	if left != 1 {
+		t.Fatal("Expected the quoted chirp to be kept as a tombstone")
+	}
+
+	decode(t, s.do(t, "DELETE", "/api/chirps/"+quote.ID.String(), quoter.Token, nil), http.StatusNoContent, nil)
+	if err := s.conn.QueryRow("SELECT COUNT(*) FROM chirps WHERE id = $1", quoted.ID).Scan(&left); err != nil {
+		t.Fatalf("Couldn't look for the tombstone: %v", err)
+	}
+	if left != 0 {
+		t.Error("Expected the tombstone to go with its last quote")
+	}
+}
@@ -9,13 +9,16 @@ func (cfg *apiConfig) handlerReset(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The calling admin's account is kept, otherwise the reset would lock
+	// them out of the next one.
+	caller, _ := requestPrincipal(r)
 	cfg.fileserverHits.Store(0)
-	err := cfg.db.Reset(r.Context())
+	err := cfg.db.Reset(r.Context(), caller.UserID)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte("Failed to reset the database: " + err.Error()))
 		return
 	}
 	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Hits reset to 0 and database reset to initial state."))
+	w.Write([]byte("Hits reset to 0 and every other account deleted."))
 }
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestReset_KeepsCallingAdmin(t *testing.T) {
+	s := newTestServer(t)
+	s.signup(t, "admin@example.com")
+	s.signup(t, "saul@example.com")
+	if _, err := s.conn.Exec("UPDATE users SET role = $1 WHERE email = $2", roleAdmin, "admin@example.com"); err != nil {
+		t.Fatalf("Couldn't promote admin: %v", err)
+	}
+	admin := s.login(t, "admin@example.com")
+
+	for i := 1; i <= 2; i++ {
+		if rec := s.do(t, "POST", "/admin/reset", admin.Token, nil); rec.Code != http.StatusOK {
+			t.Fatalf("Reset %d: expected 200, got %d: %s", i, rec.Code, rec.Body)
+		}
+	}
+
+	var count int
+	if err := s.conn.QueryRow("SELECT COUNT(*) FROM users").Scan(&count); err != nil {
+		t.Fatalf("Couldn't count users: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected only the admin to remain, got %d users", count)
+	}
+	s.login(t, "admin@example.com")
+}
+
+func TestReset_OnlyInDev(t *testing.T) {
+	s := newTestServer(t)
+	s.signup(t, "admin@example.com")
+	if _, err := s.conn.Exec("UPDATE users SET role = $1 WHERE email = $2", roleAdmin, "admin@example.com"); err != nil {
+		t.Fatalf("Couldn't promote admin: %v", err)
+	}
+	admin := s.login(t, "admin@example.com")
+	s.platform = "production"
+
+	if rec := s.do(t, "POST", "/admin/reset", admin.Token, nil); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 outside dev, got %d: %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mvusic07/Chirpy/internal/auth"
+	"github.com/mvusic07/Chirpy/internal/database"
+)
+
+const (
+	revokeKindToken   = "jti"
+	revokeKindSession = "session"
+	revokeKindUser    = "user"
+)
+
+// revocationStore is the access token denylist. Revocations are written to
+// Postgres so every instance sees them, and mirrored into memory so checking
+// a token never touches the database. A token is revoked if its jti was
+// revoked, or if it was issued no later than a revocation of its session or
+// user.
+type revocationStore struct {
+	db *database.Queries
+
+	mu       sync.RWMutex
+	tokens   map[uuid.UUID]time.Time
+	sessions map[uuid.UUID]time.Time
+	users    map[uuid.UUID]time.Time
+}
+
+func newRevocationStore(db *database.Queries) *revocationStore {
+	return &revocationStore{
+		db:       db,
+		tokens:   map[uuid.UUID]time.Time{},
+		sessions: map[uuid.UUID]time.Time{},
+		users:    map[uuid.UUID]time.Time{},
+	}
+}
+
+// RevokeToken revokes a single access token until it expires.
+func (s *revocationStore) RevokeToken(ctx context.Context, jti uuid.UUID, expiresAt time.Time) error {
+	return s.revoke(ctx, revokeKindToken, jti, time.Now().UTC(), expiresAt)
+}
+
+// RevokeSession revokes every access token issued so far for a session.
+func (s *revocationStore) RevokeSession(ctx context.Context, sessionID uuid.UUID) error {
+	now := time.Now().UTC()
+	return s.revoke(ctx, revokeKindSession, sessionID, now, now.Add(accessTokenTTL))
+}
+
+// RevokeUser revokes every access token issued so far for a user.
+func (s *revocationStore) RevokeUser(ctx context.Context, userID uuid.UUID) error {
+	now := time.Now().UTC()
+	return s.revoke(ctx, revokeKindUser, userID, now, now.Add(accessTokenTTL))
+}
+
+func (s *revocationStore) revoke(ctx context.Context, kind string, subject uuid.UUID, revokedAt, expiresAt time.Time) error {
+	err := s.db.RevokeAccessTokens(ctx, database.RevokeAccessTokensParams{
+		Kind:      kind,
+		Subject:   subject,
+		RevokedAt: revokedAt,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.add(kind, subject, revokedAt)
+	return nil
+}
+
+func (s *revocationStore) add(kind string, subject uuid.UUID, revokedAt time.Time) {
+	switch kind {
+	case revokeKindToken:
+		s.tokens[subject] = revokedAt
+	case revokeKindSession:
+		s.sessions[subject] = revokedAt
+	case revokeKindUser:
+		s.users[subject] = revokedAt
+	}
+}
+
+// IsRevoked implements auth.RevocationChecker.
+func (s *revocationStore) IsRevoked(claims *auth.Claims) bool {
+	// Tokens are compared to the microsecond, so one issued right after a
+	// revocation is good. Older tokens only carry iat, in whole seconds; one
+	// of those issued in the same second as the revocation is treated as
+	// revoked, whichever came first within it.
+	var issuedAt time.Time
+	precision := time.Microsecond
+	if claims.IssuedAtMicros != 0 {
+		issuedAt = time.UnixMicro(claims.IssuedAtMicros)
+	} else if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time.Truncate(time.Second)
+		precision = time.Second
+	}
+	userID, _ := uuid.Parse(claims.Subject)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.tokens[claims.TokenID()]; ok {
+		return true
+	}
+	if revokedAt, ok := s.sessions[claims.SessionUUID()]; ok && !issuedAt.After(revokedAt.Truncate(precision)) {
+		return true
+	}
+	if revokedAt, ok := s.users[userID]; ok && !issuedAt.After(revokedAt.Truncate(precision)) {
+		return true
+	}
+	return false
+}
+
+// Load replaces the cache with the revocations currently in the database,
+// picking up those made by other instances and dropping expired ones.
+func (s *revocationStore) Load(ctx context.Context) error {
+	started := time.Now().UTC()
+	rows, err := s.db.ListActiveAccessTokenRevocations(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokens, sessions, users := s.tokens, s.sessions, s.users
+	s.tokens = map[uuid.UUID]time.Time{}
+	s.sessions = map[uuid.UUID]time.Time{}
+	s.users = map[uuid.UUID]time.Time{}
+	for _, row := range rows {
+		s.add(row.Kind, row.Subject, row.RevokedAt)
+	}
+
+	// Keep local revocations made while the query was running.
+	for kind, entries := range map[string]map[uuid.UUID]time.Time{
+		revokeKindToken:   tokens,
+		revokeKindSession: sessions,
+		revokeKindUser:    users,
+	} {
+		for subject, revokedAt := range entries {
+			if !revokedAt.Before(started) {
+				s.add(kind, subject, revokedAt)
+			}
+		}
+	}
+	return nil
+}
+
+// Sync reloads the cache every interval and deletes expired revocations
+// until ctx is done.
+func (s *revocationStore) Sync(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := s.db.DeleteExpiredAccessTokenRevocations(ctx); err != nil {
+			log.Printf("Couldn't delete expired access token revocations: %s", err)
+		}
+		if err := s.Load(ctx); err != nil {
+			log.Printf("Couldn't load access token revocations: %s", err)
+		}
+	}
+}
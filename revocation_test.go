@@ -0,0 +1,121 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/mvusic07/Chirpy/internal/auth"
+)
+
+func TestRevocationStore_IsRevoked(t *testing.T) {
+	// Revoked partway through a second, to check tokens issued either side
+	// of it within that second.
+	now := time.Now().UTC().Truncate(time.Second).Add(300 * time.Millisecond)
+	revokedToken := uuid.New()
+	revokedSession := uuid.New()
+	revokedUser := uuid.New()
+
+	store := newRevocationStore(nil)
+	store.add(revokeKindToken, revokedToken, now)
+	store.add(revokeKindSession, revokedSession, now)
+	store.add(revokeKindUser, revokedUser, now)
+
+	claims := func(jti, sessionID, userID uuid.UUID, issuedAt time.Time) *auth.Claims {
+		return &auth.Claims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				ID:       jti.String(),
+				Subject:  userID.String(),
+				IssuedAt: jwt.NewNumericDate(issuedAt),
+			},
+			SessionID:      sessionID.String(),
+			IssuedAtMicros: issuedAt.UnixMicro(),
+		}
+	}
+	// legacy builds the claims of a token from before iat_us, which only has
+	// iat in whole seconds.
+	legacy := func(jti, sessionID, userID uuid.UUID, issuedAt time.Time) *auth.Claims {
+		c := claims(jti, sessionID, userID, issuedAt)
+		c.IssuedAtMicros = 0
+		return c
+	}
+	before := now.Add(-time.Minute)
+	after := now.Add(time.Minute)
+	sameSecondBefore := now.Add(-200 * time.Millisecond)
+	sameSecondAfter := now.Add(500 * time.Millisecond)
+	nextSecond := now.Add(700 * time.Millisecond)
+
+	tests := []struct {
+		name   string
+		claims *auth.Claims
+		want   bool
+	}{
+		{"unrelated token", claims(uuid.New(), uuid.New(), uuid.New(), before), false},
+		{"revoked jti", claims(revokedToken, uuid.New(), uuid.New(), before), true},
+		{"revoked session, issued before", claims(uuid.New(), revokedSession, uuid.New(), before), true},
+		{"revoked session, issued after", claims(uuid.New(), revokedSession, uuid.New(), after), false},
+		{"revoked user, issued before", claims(uuid.New(), uuid.New(), revokedUser, before), true},
+		{"revoked user, issued after", claims(uuid.New(), uuid.New(), revokedUser, after), false},
+		{"revoked session, same second before", claims(uuid.New(), revokedSession, uuid.New(), sameSecondBefore), true},
+		{"revoked session, same second after", claims(uuid.New(), revokedSession, uuid.New(), sameSecondAfter), false},
+		{"revoked session, same microsecond", claims(uuid.New(), revokedSession, uuid.New(), now), true},
+		{"revoked user, same second before", claims(uuid.New(), uuid.New(), revokedUser, sameSecondBefore), true},
+		{"revoked user, same second after", claims(uuid.New(), uuid.New(), revokedUser, sameSecondAfter), false},
+		{"revoked user, next second", claims(uuid.New(), uuid.New(), revokedUser, nextSecond), false},
+		{"legacy, revoked session, same second before", legacy(uuid.New(), revokedSession, uuid.New(), sameSecondBefore), true},
+		{"legacy, revoked session, same second after", legacy(uuid.New(), revokedSession, uuid.New(), sameSecondAfter), true},
+		{"legacy, revoked user, same second after", legacy(uuid.New(), uuid.New(), revokedUser, sameSecondAfter), true},
+		{"legacy, revoked user, next second", legacy(uuid.New(), uuid.New(), revokedUser, nextSecond), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := store.IsRevoked(tt.claims); got != tt.want {
+				t.Errorf("Expected IsRevoked %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRevocationStore_TokenIssuedRightAfterRevocation(t *testing.T) {
+	keys, err := auth.LoadKeySet(t.TempDir())
+	if err != nil {
+		t.Fatalf("LoadKeySet failed: %v", err)
+	}
+	store := newRevocationStore(nil)
+	keys.SetRevocationChecker(store)
+	userID, sessionID := uuid.New(), uuid.New()
+
+	// Revoking and issuing land in the same second, as when logging in
+	// again straight after signing out everywhere.
+	store.add(revokeKindUser, userID, time.Now().UTC())
+	store.add(revokeKindSession, sessionID, time.Now().UTC())
+	// A login takes far longer than this; without it the two could share a
+	// microsecond, which counts as revoked.
+	time.Sleep(time.Millisecond)
+	token, err := keys.MakeJWT(userID, sessionID, time.Minute)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %v", err)
+	}
+	if _, err := keys.ParseJWT(token); err != nil {
+		t.Errorf("Expected a token issued after the revocation to be valid, got %v", err)
+	}
+}
+
+func TestRevokeAll_ThenLogInAgain(t *testing.T) {
+	s := newTestServer(t)
+	session := s.signup(t, "francesca@example.com")
+
+	decode(t, s.do(t, "POST", "/api/sessions/revoke-all", session.Token, nil), http.StatusNoContent, nil)
+	// Usually within the same second as the revocation.
+	again := s.login(t, "francesca@example.com")
+
+	if rec := s.do(t, "GET", "/api/sessions", again.Token, nil); rec.Code != http.StatusOK {
+		t.Errorf("Expected the new access token to work, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := s.do(t, "GET", "/api/sessions", session.Token, nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the old access token to be revoked, got %d: %s", rec.Code, rec.Body)
+	}
+}
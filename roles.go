@@ -0,0 +1,38 @@
+package main
+
+import "slices"
+
+// Roles, in increasing order of privilege. Every account starts as a user;
+// see the set-role command for creating the first admin.
+const (
+	roleUser      = "user"
+	roleModerator = "moderator"
+	roleAdmin     = "admin"
+)
+
+var knownRoles = []string{roleUser, roleModerator, roleAdmin}
+
+// Permissions guard the admin API. Routes ask for a permission rather than a
+// role so that what each role may do is decided here, in one place.
+const (
+	permModerateUsers = "users:moderate"
+	permManageUsers   = "users:manage"
+	permReadMetrics   = "metrics:read"
+	permResetData     = "admin:reset"
+)
+
+var rolePermissions = map[string][]string{
+	roleModerator: {permModerateUsers},
+	roleAdmin:     {permModerateUsers, permManageUsers, permReadMetrics, permResetData},
+}
+
+// roleHasPermission reports whether role grants perm. Unknown roles, and
+// tokens issued before roles existed, get nothing beyond a plain user.
+func roleHasPermission(role, perm string) bool {
+	return slices.Contains(rolePermissions[role], perm)
+}
+
+// outranks reports whether role a is strictly more privileged than role b.
+func outranks(a, b string) bool {
+	return slices.Index(knownRoles, a) > slices.Index(knownRoles, b)
+}
@@ -0,0 +1,19 @@
+-- name: RevokeAccessTokens :exec
+INSERT INTO access_token_revocations (kind, subject, revoked_at, expires_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT (kind, subject) DO UPDATE SET
+revoked_at = EXCLUDED.revoked_at,
+expires_at = EXCLUDED.expires_at;
+
+-- name: ListActiveAccessTokenRevocations :many
+SELECT * FROM access_token_revocations
+WHERE expires_at > NOW();
+
+-- name: DeleteExpiredAccessTokenRevocations :exec
+DELETE FROM access_token_revocations
+WHERE expires_at <= NOW();
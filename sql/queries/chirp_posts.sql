@@ -0,0 +1,14 @@
+-- name: RecordChirpPost :exec
+INSERT INTO chirp_posts (user_id, created_at)
+VALUES ($1, $2);
+
+-- name: PruneChirpPosts :exec
+DELETE FROM chirp_posts
+WHERE user_id = sqlc.arg('user_id')
+AND created_at <= sqlc.arg('before')::timestamp;
+
+-- name: GetChirpRateWindow :one
+SELECT COUNT(*)::int AS count, COALESCE(MIN(created_at), NOW())::timestamp AS oldest
+FROM chirp_posts
+WHERE user_id = sqlc.arg('user_id')
+AND created_at > sqlc.arg('since')::timestamp;
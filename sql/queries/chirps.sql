@@ -1,18 +1,52 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
-VALUES (
-    gen_random_uuid(),
-    NOW(),
-    NOW(),
-    $1,
-    $2
+-- A chirp that isn't a reply starts its own conversation.
+WITH new_chirp AS (
+    SELECT gen_random_uuid() AS id
 )
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to_id, conversation_id, rechirp_of_id, quote_of_id)
+SELECT
+    new_chirp.id,
+    NOW() AS created_at,
+    NOW() AS updated_at,
+    sqlc.arg('body')::text AS body,
+    sqlc.arg('user_id')::uuid AS user_id,
+    sqlc.narg('in_reply_to_id')::uuid AS in_reply_to_id,
+    COALESCE(sqlc.narg('conversation_id')::uuid, new_chirp.id) AS conversation_id,
+    sqlc.narg('rechirp_of_id')::uuid AS rechirp_of_id,
+    sqlc.narg('quote_of_id')::uuid AS quote_of_id
+FROM new_chirp
 RETURNING *;
 
 
--- name: GetChirps :many
+-- name: ListChirpsAsc :many
 SELECT * FROM chirps
-ORDER BY created_at ASC;
+WHERE deleted_at IS NULL
+AND (created_at, id) > (sqlc.arg('cursor_created_at')::timestamp, sqlc.arg('cursor_id')::uuid)
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: ListChirpsDesc :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+AND (created_at, id) < (sqlc.arg('cursor_created_at')::timestamp, sqlc.arg('cursor_id')::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ListChirpsByAuthorAsc :many
+SELECT * FROM chirps
+WHERE user_id = sqlc.arg('user_id')::uuid
+AND deleted_at IS NULL
+AND (created_at, id) > (sqlc.arg('cursor_created_at')::timestamp, sqlc.arg('cursor_id')::uuid)
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: ListChirpsByAuthorDesc :many
+SELECT * FROM chirps
+WHERE user_id = sqlc.arg('user_id')::uuid
+AND deleted_at IS NULL
+AND (created_at, id) < (sqlc.arg('cursor_created_at')::timestamp, sqlc.arg('cursor_id')::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
 
 -- name: GetChirpById :one
 SELECT * FROM chirps
@@ -21,4 +55,78 @@ WHERE id = $1;
 
 -- name: DeleteChirpById :exec
 DELETE FROM chirps
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: TombstoneChirp :exec
+UPDATE chirps SET body = '', deleted_at = NOW(), updated_at = NOW()
+WHERE id = $1;
+
+-- name: GetChirpForUpdate :one
+SELECT * FROM chirps
+WHERE id = $1
+FOR UPDATE;
+
+-- name: ListChirpAncestors :many
+-- The chain of parents up to the root, root first. Very deep threads are cut
+-- off at the 100 nearest ancestors.
+WITH RECURSIVE ancestors AS (
+    SELECT parent.*, 1 AS depth
+    FROM chirps parent
+    WHERE parent.id = (SELECT in_reply_to_id FROM chirps WHERE chirps.id = sqlc.arg('id'))
+    UNION ALL
+    SELECT parent.*, ancestors.depth + 1
+    FROM chirps parent
+    JOIN ancestors ON parent.id = ancestors.in_reply_to_id
+    WHERE ancestors.depth < 100
+)
+SELECT id, created_at, updated_at, body, user_id, in_reply_to_id, conversation_id, reply_count, deleted_at, like_count,
+    rechirp_of_id, quote_of_id, rechirp_count, quote_count
+FROM ancestors
+ORDER BY depth DESC;
+
+-- name: ListReplies :many
+SELECT * FROM chirps
+WHERE in_reply_to_id = sqlc.arg('parent_id')
+AND (created_at, id) > (sqlc.arg('cursor_created_at')::timestamp, sqlc.arg('cursor_id')::uuid)
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: ListReplyPreviews :many
+-- The first replies to each of several chirps, for nesting in a thread.
+SELECT id, created_at, updated_at, body, user_id, in_reply_to_id, conversation_id, reply_count, deleted_at, like_count,
+    rechirp_of_id, quote_of_id, rechirp_count, quote_count
+FROM (
+    SELECT chirps.*, row_number() OVER (PARTITION BY in_reply_to_id ORDER BY created_at, id) AS position
+    FROM chirps
+    WHERE in_reply_to_id = ANY(sqlc.arg('parent_ids')::uuid[])
+) replies
+WHERE position <= sqlc.arg('per_parent')::int
+ORDER BY created_at ASC, id ASC;
+
+-- name: ListAllChirpsByAuthor :many
+SELECT * FROM chirps
+WHERE user_id = sqlc.arg('user_id')::uuid
+AND deleted_at IS NULL
+ORDER BY created_at ASC, id ASC;
+
+-- name: GetRechirp :one
+SELECT * FROM chirps
+WHERE user_id = sqlc.arg('user_id')::uuid
+AND rechirp_of_id = sqlc.arg('rechirp_of_id');
+
+-- name: DeleteRechirp :execrows
+DELETE FROM chirps
+WHERE user_id = sqlc.arg('user_id')::uuid
+AND rechirp_of_id = sqlc.arg('rechirp_of_id');
+
+-- name: DeleteRechirpsOf :exec
+DELETE FROM chirps
+WHERE rechirp_of_id = $1;
+
+-- name: ListSharedChirps :many
+-- The chirps that rechirps and quotes refer to, with whether their author's
+-- account is hidden (suspended or closing).
+SELECT sqlc.embed(chirps), (users.suspended_at IS NOT NULL OR users.delete_after IS NOT NULL)::bool AS author_hidden
+FROM chirps
+JOIN users ON users.id = chirps.user_id
+WHERE chirps.id = ANY(sqlc.arg('ids')::uuid[]);
@@ -0,0 +1,55 @@
+-- name: CreateDataExport :one
+INSERT INTO data_exports (id, created_at, updated_at, user_id)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1
+)
+RETURNING id, created_at, updated_at, user_id, status, error, completed_at, expires_at;
+
+-- name: GetUnfinishedDataExport :one
+SELECT id, created_at, updated_at, user_id, status, error, completed_at, expires_at
+FROM data_exports
+WHERE user_id = $1
+AND status IN ('pending', 'running')
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: GetDataExportStatus :one
+SELECT id, created_at, updated_at, user_id, status, error, completed_at, expires_at
+FROM data_exports
+WHERE id = $1
+AND user_id = $2;
+
+-- name: GetDataExportArchive :one
+SELECT archive FROM data_exports
+WHERE id = $1
+AND user_id = $2
+AND status = 'complete'
+AND expires_at > NOW();
+
+-- name: ClaimDataExport :one
+-- Takes the oldest pending export, or one whose worker seems to have died.
+UPDATE data_exports SET status = 'running', updated_at = NOW()
+WHERE id = (
+    SELECT id FROM data_exports
+    WHERE status = 'pending'
+    OR (status = 'running' AND updated_at < sqlc.arg('stale_before')::timestamp)
+    ORDER BY created_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, created_at, updated_at, user_id, status, error, completed_at, expires_at;
+
+-- name: CompleteDataExport :exec
+UPDATE data_exports SET status = 'complete', archive = $2, completed_at = NOW(), expires_at = $3, updated_at = NOW()
+WHERE id = $1;
+
+-- name: FailDataExport :exec
+UPDATE data_exports SET status = 'failed', error = $2, completed_at = NOW(), updated_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteExpiredDataExports :exec
+DELETE FROM data_exports
+WHERE expires_at <= NOW();
@@ -0,0 +1,36 @@
+-- name: LikeChirp :execrows
+INSERT INTO likes (user_id, chirp_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: UnlikeChirp :execrows
+DELETE FROM likes
+WHERE user_id = $1
+AND chirp_id = $2;
+
+-- name: ListLikedChirpIDs :many
+-- Which of the given chirps the user has liked.
+SELECT chirp_id FROM likes
+WHERE user_id = sqlc.arg('user_id')
+AND chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[]);
+
+-- name: ListChirpLikers :many
+SELECT sqlc.embed(users), likes.created_at AS liked_at
+FROM likes
+JOIN users ON users.id = likes.user_id
+WHERE likes.chirp_id = sqlc.arg('chirp_id')
+AND users.suspended_at IS NULL
+AND users.delete_after IS NULL
+AND (likes.created_at, likes.user_id) < (sqlc.arg('cursor_created_at')::timestamp, sqlc.arg('cursor_id')::uuid)
+ORDER BY likes.created_at DESC, likes.user_id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ListLikedChirps :many
+SELECT sqlc.embed(chirps), likes.created_at AS liked_at
+FROM likes
+JOIN chirps ON chirps.id = likes.chirp_id
+WHERE likes.user_id = sqlc.arg('user_id')
+AND chirps.deleted_at IS NULL
+AND (likes.created_at, likes.chirp_id) < (sqlc.arg('cursor_created_at')::timestamp, sqlc.arg('cursor_id')::uuid)
+ORDER BY likes.created_at DESC, likes.chirp_id DESC
+LIMIT sqlc.arg('limit');
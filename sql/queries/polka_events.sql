@@ -0,0 +1,4 @@
+-- name: RecordPolkaEvent :execrows
+INSERT INTO polka_events (id, event, user_id)
+VALUES ($1, $2, $3)
+ON CONFLICT (id) DO NOTHING;
@@ -1,2 +1,2 @@
 -- name: Reset :exec
-DELETE FROM users;
+DELETE FROM users WHERE id <> sqlc.arg(keep_user_id);
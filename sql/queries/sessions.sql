@@ -0,0 +1,75 @@
+-- name: CreateSession :one
+INSERT INTO sessions (id, created_at, updated_at, user_id, name, user_agent, ip, last_used_at)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    NOW()
+)
+RETURNING *;
+
+-- name: CreateClientSession :one
+INSERT INTO sessions (id, created_at, updated_at, user_id, name, user_agent, ip, last_used_at, client_id, scopes)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    NOW(),
+    $5,
+    $6
+)
+RETURNING *;
+
+-- name: GetSession :one
+SELECT * FROM sessions
+WHERE id = $1;
+
+-- name: TouchSession :one
+UPDATE sessions SET last_used_at = NOW(),
+user_agent = $2,
+ip = $3,
+updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: ListActiveSessions :many
+SELECT * FROM sessions
+WHERE sessions.user_id = $1
+AND sessions.revoked_at IS NULL
+AND EXISTS (
+    SELECT 1 FROM refresh_tokens
+    WHERE refresh_tokens.family_id = sessions.id
+    AND refresh_tokens.revoked_at IS NULL
+    AND refresh_tokens.expires_at > NOW()
+)
+ORDER BY last_used_at DESC;
+
+-- name: RenameSession :one
+UPDATE sessions SET name = $3,
+updated_at = NOW()
+WHERE id = $1
+AND user_id = $2
+AND revoked_at IS NULL
+RETURNING *;
+
+-- name: RevokeSession :one
+UPDATE sessions SET revoked_at = NOW(),
+updated_at = NOW()
+WHERE id = $1
+AND user_id = $2
+AND revoked_at IS NULL
+RETURNING *;
+
+-- name: RevokeAllSessions :exec
+UPDATE sessions SET revoked_at = NOW(),
+updated_at = NOW()
+WHERE user_id = $1
+AND revoked_at IS NULL;
@@ -0,0 +1,97 @@
+-- name: ListTimeline :many
+-- Fan-out on read: the caller's own chirps and those of everyone they follow.
+SELECT chirps.* FROM chirps
+WHERE (chirps.user_id = sqlc.arg('user_id')::uuid
+    OR chirps.user_id IN (SELECT followee_id FROM follows WHERE follower_id = sqlc.arg('user_id')))
+AND chirps.deleted_at IS NULL
+AND (chirps.created_at, chirps.id) < (sqlc.arg('cursor_created_at')::timestamp, sqlc.arg('cursor_id')::uuid)
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ListPrecomputedTimeline :many
+-- Chirps by followees who fan out on write come from timeline_entries; the
+-- caller's own and the other followees' are merged in at read time. Each
+-- side is cut to the page size before merging.
+SELECT chirps.* FROM chirps
+JOIN (
+    (SELECT timeline_entries.chirp_id AS id
+    FROM timeline_entries
+    JOIN chirps ON chirps.id = timeline_entries.chirp_id
+    WHERE timeline_entries.user_id = sqlc.arg('user_id')
+    AND chirps.deleted_at IS NULL
+    AND (timeline_entries.created_at, timeline_entries.chirp_id) < (sqlc.arg('cursor_created_at')::timestamp, sqlc.arg('cursor_id')::uuid)
+    ORDER BY timeline_entries.created_at DESC, timeline_entries.chirp_id DESC
+    LIMIT sqlc.arg('limit'))
+    UNION
+    (SELECT chirps.id
+    FROM chirps
+    WHERE (chirps.user_id = sqlc.arg('user_id')
+        OR chirps.user_id IN (
+            SELECT follows.followee_id FROM follows
+            JOIN users ON users.id = follows.followee_id
+            WHERE follows.follower_id = sqlc.arg('user_id')
+            AND NOT users.fan_out_on_write))
+    AND chirps.deleted_at IS NULL
+    AND (chirps.created_at, chirps.id) < (sqlc.arg('cursor_created_at')::timestamp, sqlc.arg('cursor_id')::uuid)
+    ORDER BY chirps.created_at DESC, chirps.id DESC
+    LIMIT sqlc.arg('limit'))
+) page ON page.id = chirps.id
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: FollowsFanOutAuthor :one
+-- Whether any of the user's followees fans out on write, so that their
+-- timeline has precomputed entries to read.
+SELECT EXISTS (
+    SELECT 1 FROM follows
+    JOIN users ON users.id = follows.followee_id
+    WHERE follows.follower_id = $1
+    AND users.fan_out_on_write
+)::boolean AS follows_fan_out_author;
+
+-- name: EnableFanOutOnWrite :execrows
+UPDATE users SET fan_out_on_write = TRUE, updated_at = NOW()
+WHERE id = $1
+AND NOT fan_out_on_write;
+
+-- name: FanOutChirp :exec
+-- Adds a new chirp to the timelines of its author's followers, if the author
+-- fans out on write.
+INSERT INTO timeline_entries (user_id, chirp_id, author_id, created_at)
+SELECT follows.follower_id, sqlc.arg('chirp_id'), sqlc.arg('author_id'), sqlc.arg('created_at')
+FROM follows
+JOIN users ON users.id = follows.followee_id
+WHERE follows.followee_id = sqlc.arg('author_id')
+AND users.fan_out_on_write
+ON CONFLICT DO NOTHING;
+
+-- name: BackfillTimeline :exec
+-- Copies an author's latest chirps into one follower's timeline.
+INSERT INTO timeline_entries (user_id, chirp_id, author_id, created_at)
+SELECT sqlc.arg('user_id'), chirps.id, chirps.user_id, chirps.created_at
+FROM chirps
+WHERE chirps.user_id = sqlc.arg('author_id')::uuid
+AND chirps.deleted_at IS NULL
+ORDER BY chirps.created_at DESC
+LIMIT sqlc.arg('limit')
+ON CONFLICT DO NOTHING;
+
+-- name: BackfillFollowerTimelines :exec
+-- Copies an author's latest chirps into every follower's timeline.
+INSERT INTO timeline_entries (user_id, chirp_id, author_id, created_at)
+SELECT follows.follower_id, recent.id, recent.user_id, recent.created_at
+FROM follows
+CROSS JOIN (
+    SELECT chirps.id, chirps.user_id, chirps.created_at FROM chirps
+    WHERE chirps.user_id = sqlc.arg('author_id')::uuid
+    AND chirps.deleted_at IS NULL
+    ORDER BY chirps.created_at DESC
+    LIMIT sqlc.arg('limit')
+) recent
+WHERE follows.followee_id = sqlc.arg('author_id')
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveTimelineAuthor :exec
+DELETE FROM timeline_entries
+WHERE user_id = $1
+AND author_id = $2;
@@ -1,19 +1,123 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email,hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3
 )
 RETURNING *;
 
 -- name: GetUserByEmail :one
+-- Expects a normalized email; LOWER also finds accounts stored before emails
+-- were normalized.
 SELECT * FROM users
-WHERE email = $1;
+WHERE LOWER(email) = LOWER(sqlc.arg('email'));
+
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE id = $1;
+
+-- name: GetUserForUpdate :one
+SELECT * FROM users
+WHERE id = $1
+FOR UPDATE;
 
 -- name: UpdateUser :one
 UPDATE users SET email = $2, hashed_password = $3, updated_at = NOW()
 WHERE id = $1
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: UpdateUserPassword :one
+UPDATE users SET hashed_password = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetPendingEmail :one
+UPDATE users SET pending_email = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: VerifyUserEmail :one
+UPDATE users SET email = $2,
+email_verified_at = NOW(),
+pending_email = NULL,
+updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetUserRole :one
+UPDATE users SET role = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SuspendUser :one
+UPDATE users SET suspended_at = NOW(), updated_at = NOW()
+WHERE id = $1
+AND suspended_at IS NULL
+RETURNING *;
+
+-- name: UnsuspendUser :one
+UPDATE users SET suspended_at = NULL, updated_at = NOW()
+WHERE id = $1
+AND suspended_at IS NOT NULL
+RETURNING *;
+
+-- name: UpgradeToChirpyRed :one
+UPDATE users SET is_chirpy_red = TRUE, chirpy_red_until = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DowngradeFromChirpyRed :one
+UPDATE users SET is_chirpy_red = FALSE, chirpy_red_until = NULL, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: ScheduleUserDeletion :one
+UPDATE users SET delete_after = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: CancelUserDeletion :exec
+UPDATE users SET delete_after = NULL, updated_at = NOW()
+WHERE id = $1
+AND delete_after IS NOT NULL;
+
+-- name: DeleteUser :execrows
+DELETE FROM users
+WHERE id = $1;
+
+-- name: DeleteScheduledUsers :many
+DELETE FROM users
+WHERE delete_after <= NOW()
+RETURNING id;
+
+-- name: ListUserEmails :many
+SELECT id, email FROM users
+ORDER BY created_at;
+
+-- name: SetUserEmail :exec
+UPDATE users SET email = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: GetUserByHandle :one
+SELECT * FROM users
+WHERE LOWER(handle) = LOWER(sqlc.arg('handle'));
+
+-- name: UpdateProfile :one
+UPDATE users SET
+    handle = COALESCE(sqlc.narg('handle'), handle),
+    display_name = COALESCE(sqlc.narg('display_name'), display_name),
+    bio = COALESCE(sqlc.narg('bio'), bio),
+    avatar_url = COALESCE(sqlc.narg('avatar_url'), avatar_url),
+    location = COALESCE(sqlc.narg('location'), location),
+    updated_at = NOW()
+WHERE id = sqlc.arg('id')
+RETURNING *;
+
+-- name: GetAuthorSummaries :many
+SELECT id, handle, display_name, avatar_url, is_chirpy_red, chirpy_red_until
+FROM users
+WHERE id = ANY(sqlc.arg('ids')::uuid[]);
@@ -0,0 +1,23 @@
+-- +goose Up
+-- Refresh tokens are 256 bits of randomness, so an unsalted SHA-256 digest is
+-- enough to make a leaked table useless. Existing rows are hashed in place and
+-- keep working because lookups hash the presented token the same way.
+UPDATE refresh_tokens SET
+token = encode(sha256(convert_to(token, 'UTF8')), 'hex'),
+parent_token = encode(sha256(convert_to(parent_token, 'UTF8')), 'hex');
+
+ALTER TABLE refresh_tokens
+RENAME COLUMN token TO token_hash;
+
+ALTER TABLE refresh_tokens
+RENAME COLUMN parent_token TO parent_token_hash;
+
+-- +goose Down
+-- Digests cannot be reversed: rolling back signs every session out.
+DELETE FROM refresh_tokens;
+
+ALTER TABLE refresh_tokens
+RENAME COLUMN parent_token_hash TO parent_token;
+
+ALTER TABLE refresh_tokens
+RENAME COLUMN token_hash TO token;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- Revoked access tokens, by jti, or every token of a session or user issued
+-- up to revoked_at. Rows are only needed until the tokens they cover expire.
+CREATE TABLE access_token_revocations (
+    kind TEXT NOT NULL CHECK (kind IN ('jti', 'session', 'user')),
+    subject UUID NOT NULL,
+    revoked_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (kind, subject)
+);
+
+CREATE INDEX access_token_revocations_expires_at_idx ON access_token_revocations (expires_at);
+
+-- +goose Down
+DROP TABLE access_token_revocations;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- Failed login counters, keyed by "account:<email>" or "ip:<address>". Keys
+-- are created for unknown emails too so lockouts don't reveal which exist.
+CREATE TABLE login_throttles (
+    key TEXT PRIMARY KEY,
+    failures INTEGER NOT NULL,
+    last_failure_at TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE login_throttles;
@@ -0,0 +1,46 @@
+-- +goose Up
+CREATE TABLE oauth_clients (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    -- NULL for public clients, such as mobile and single-page apps, which
+    -- can't keep a secret and rely on PKCE alone.
+    secret_hash TEXT,
+    redirect_uris TEXT[] NOT NULL,
+    scopes TEXT[] NOT NULL
+);
+
+CREATE INDEX oauth_clients_owner_id_idx ON oauth_clients (owner_id);
+
+-- A session with a client belongs to a third-party app; its scopes cap what
+-- the app's access tokens may do.
+ALTER TABLE sessions
+ADD COLUMN client_id UUID REFERENCES oauth_clients(id) ON DELETE CASCADE,
+ADD COLUMN scopes TEXT[];
+
+CREATE INDEX sessions_client_id_idx ON sessions (client_id);
+
+CREATE TABLE oauth_authorization_codes (
+    code_hash TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    client_id UUID NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    redirect_uri TEXT NOT NULL,
+    scopes TEXT[] NOT NULL,
+    code_challenge TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    -- The session the code was exchanged for, revoked if the code is replayed.
+    session_id UUID REFERENCES sessions(id) ON DELETE SET NULL
+);
+
+-- +goose Down
+DROP TABLE oauth_authorization_codes;
+
+ALTER TABLE sessions
+DROP COLUMN scopes,
+DROP COLUMN client_id;
+
+DROP TABLE oauth_clients;
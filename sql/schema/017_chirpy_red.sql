@@ -0,0 +1,20 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN is_chirpy_red BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN chirpy_red_until TIMESTAMP;
+
+-- Webhook events already handled, so redeliveries are acknowledged without
+-- being applied twice.
+CREATE TABLE polka_events (
+    id TEXT PRIMARY KEY,
+    received_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    event TEXT NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE polka_events;
+
+ALTER TABLE users
+DROP COLUMN chirpy_red_until,
+DROP COLUMN is_chirpy_red;
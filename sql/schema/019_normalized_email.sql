@@ -0,0 +1,34 @@
+-- +goose Up
+-- Emails are now stored normalized (see auth.NormalizeEmail) and are unique
+-- regardless of case. Accounts registered before then may already collide;
+-- list them and stop, rather than pick a winner here. Collisions that only
+-- normalization beyond case reveals are reported by `chirpy normalize-emails`.
+-- +goose StatementBegin
+DO $$
+DECLARE
+    collision RECORD;
+    collisions INT := 0;
+BEGIN
+    FOR collision IN
+        SELECT LOWER(email) AS email,
+            string_agg(id::text || ' <' || email || '>', ', ' ORDER BY created_at) AS accounts
+        FROM users
+        GROUP BY LOWER(email)
+        HAVING COUNT(*) > 1
+    LOOP
+        RAISE WARNING 'accounts sharing email %: %', collision.email, collision.accounts;
+        collisions := collisions + 1;
+    END LOOP;
+    IF collisions > 0 THEN
+        RAISE EXCEPTION '% emails belong to more than one account; merge or rename them and migrate again', collisions;
+    END IF;
+END
+$$;
+-- +goose StatementEnd
+
+ALTER TABLE users DROP CONSTRAINT users_email_key;
+CREATE UNIQUE INDEX users_email_lower_idx ON users (LOWER(email));
+
+-- +goose Down
+DROP INDEX users_email_lower_idx;
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
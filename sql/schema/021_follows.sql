@@ -0,0 +1,37 @@
+-- +goose Up
+CREATE TABLE follows (
+    follower_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    followee_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (follower_id, followee_id),
+    CHECK (follower_id <> followee_id)
+);
+
+-- Keyset pagination of either side of the graph, newest first.
+CREATE INDEX follows_follower_idx ON follows (follower_id, created_at, followee_id);
+CREATE INDEX follows_followee_idx ON follows (followee_id, created_at, follower_id);
+
+-- Users who follow many accounts read their timeline from timeline_entries,
+-- which is filled in as chirps are posted, instead of merging every
+-- followee's chirps on each read.
+ALTER TABLE users
+ADD COLUMN precomputed_timeline BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE timeline_entries (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+    author_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, chirp_id)
+);
+
+CREATE INDEX timeline_entries_page_idx ON timeline_entries (user_id, created_at, chirp_id);
+CREATE INDEX timeline_entries_author_idx ON timeline_entries (user_id, author_id);
+
+-- +goose Down
+DROP TABLE timeline_entries;
+
+ALTER TABLE users
+DROP COLUMN precomputed_timeline;
+
+DROP TABLE follows;
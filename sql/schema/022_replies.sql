@@ -0,0 +1,29 @@
+-- +goose Up
+-- conversation_id is the root chirp of the thread; a root is its own
+-- conversation. reply_count counts direct replies still in the thread,
+-- tombstones included. A chirp deleted while it has replies is kept as a
+-- tombstone (deleted_at set, body cleared) so the thread stays connected.
+ALTER TABLE chirps
+ADD COLUMN in_reply_to_id UUID REFERENCES chirps(id) ON DELETE SET NULL,
+ADD COLUMN conversation_id UUID,
+ADD COLUMN reply_count INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN deleted_at TIMESTAMP;
+
+UPDATE chirps SET conversation_id = id;
+
+ALTER TABLE chirps ALTER COLUMN conversation_id SET NOT NULL;
+
+CREATE INDEX chirps_in_reply_to_idx ON chirps (in_reply_to_id, created_at, id);
+CREATE INDEX chirps_conversation_idx ON chirps (conversation_id);
+
+-- +goose Down
+DROP INDEX chirps_conversation_idx;
+DROP INDEX chirps_in_reply_to_idx;
+
+DELETE FROM chirps WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE chirps
+DROP COLUMN deleted_at,
+DROP COLUMN reply_count,
+DROP COLUMN conversation_id,
+DROP COLUMN in_reply_to_id;
@@ -0,0 +1,54 @@
+-- +goose Up
+-- A rechirp shares another chirp as is: it has no body of its own and goes
+-- away with the original. A quote adds a body; if the original's account is
+-- deleted the quote stays, without it.
+ALTER TABLE chirps
+ADD COLUMN rechirp_of_id UUID REFERENCES chirps(id) ON DELETE CASCADE,
+ADD COLUMN quote_of_id UUID REFERENCES chirps(id) ON DELETE SET NULL,
+ADD COLUMN rechirp_count INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN quote_count INTEGER NOT NULL DEFAULT 0;
+
+-- Each user can rechirp a chirp once.
+CREATE UNIQUE INDEX chirps_rechirp_idx ON chirps (user_id, rechirp_of_id)
+WHERE rechirp_of_id IS NOT NULL;
+CREATE INDEX chirps_quote_of_idx ON chirps (quote_of_id)
+WHERE quote_of_id IS NOT NULL;
+
+-- Like like_count, the counters are kept by a trigger so that cascades are
+-- counted. A tombstoned quote no longer counts.
+-- +goose StatementBegin
+CREATE FUNCTION update_chirp_share_counts() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        UPDATE chirps SET rechirp_count = rechirp_count + 1 WHERE id = NEW.rechirp_of_id;
+        UPDATE chirps SET quote_count = quote_count + 1 WHERE id = NEW.quote_of_id;
+    ELSIF TG_OP = 'DELETE' THEN
+        UPDATE chirps SET rechirp_count = rechirp_count - 1 WHERE id = OLD.rechirp_of_id;
+        IF OLD.deleted_at IS NULL THEN
+            UPDATE chirps SET quote_count = quote_count - 1 WHERE id = OLD.quote_of_id;
+        END IF;
+    ELSIF OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN
+        UPDATE chirps SET quote_count = quote_count - 1 WHERE id = NEW.quote_of_id;
+    END IF;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER chirps_share_counts
+AFTER INSERT OR DELETE OR UPDATE OF deleted_at ON chirps
+FOR EACH ROW EXECUTE FUNCTION update_chirp_share_counts();
+
+-- +goose Down
+DROP TRIGGER chirps_share_counts ON chirps;
+DROP FUNCTION update_chirp_share_counts();
+DROP INDEX chirps_quote_of_idx;
+DROP INDEX chirps_rechirp_idx;
+
+DELETE FROM chirps WHERE rechirp_of_id IS NOT NULL;
+
+ALTER TABLE chirps
+DROP COLUMN quote_count,
+DROP COLUMN rechirp_count,
+DROP COLUMN quote_of_id,
+DROP COLUMN rechirp_of_id;
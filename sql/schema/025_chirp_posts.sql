@@ -0,0 +1,18 @@
+-- +goose Up
+-- The posting rate limit counts rows here rather than chirps, so deleting a
+-- chirp doesn't hand back its slot. Rows older than the limit's window are
+-- pruned as new ones are added.
+CREATE TABLE chirp_posts (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX chirp_posts_user_created_idx ON chirp_posts (user_id, created_at);
+
+INSERT INTO chirp_posts (user_id, created_at)
+SELECT user_id, created_at FROM chirps
+WHERE rechirp_of_id IS NULL
+AND created_at > NOW() - INTERVAL '1 hour';
+
+-- +goose Down
+DROP TABLE chirp_posts;
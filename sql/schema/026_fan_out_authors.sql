@@ -0,0 +1,14 @@
+-- +goose Up
+-- Timelines are now precomputed per author rather than per reader: chirps
+-- by accounts with many followers are copied into their followers'
+-- timeline_entries as they are posted, and everyone else's are merged in at
+-- read time. Entries made the old way are dropped; authors are switched over
+-- again as they gain followers.
+ALTER TABLE users RENAME COLUMN precomputed_timeline TO fan_out_on_write;
+UPDATE users SET fan_out_on_write = FALSE;
+DELETE FROM timeline_entries;
+
+-- +goose Down
+UPDATE users SET fan_out_on_write = FALSE;
+DELETE FROM timeline_entries;
+ALTER TABLE users RENAME COLUMN fan_out_on_write TO precomputed_timeline;
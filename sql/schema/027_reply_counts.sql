@@ -0,0 +1,79 @@
+-- +goose Up
+-- Like like_count, reply_count is now kept by a trigger so that replies
+-- removed by a cascade (a deleted account) are counted too. A tombstone is
+-- only kept while it has replies or quotes, so one that loses its last reply
+-- goes with it.
+-- +goose StatementBegin
+CREATE FUNCTION update_chirp_reply_count() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND OLD.in_reply_to_id IS NOT DISTINCT FROM NEW.in_reply_to_id THEN
+        RETURN NULL;
+    END IF;
+    IF TG_OP <> 'INSERT' THEN
+        UPDATE chirps SET reply_count = reply_count - 1 WHERE id = OLD.in_reply_to_id;
+        DELETE FROM chirps
+        WHERE id = OLD.in_reply_to_id
+        AND deleted_at IS NOT NULL AND reply_count = 0 AND quote_count = 0;
+    END IF;
+    IF TG_OP <> 'DELETE' THEN
+        UPDATE chirps SET reply_count = reply_count + 1 WHERE id = NEW.in_reply_to_id;
+    END IF;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER chirps_reply_count
+AFTER INSERT OR DELETE OR UPDATE OF in_reply_to_id ON chirps
+FOR EACH ROW EXECUTE FUNCTION update_chirp_reply_count();
+
+UPDATE chirps SET reply_count = (
+    SELECT COUNT(*) FROM chirps replies WHERE replies.in_reply_to_id = chirps.id
+);
+
+-- A deleted account's chirps go the way deleteChirp would take them one by
+-- one: those others replied to or quoted become tombstones, without an
+-- author, and the rest are deleted along with its rechirps.
+ALTER TABLE chirps
+DROP CONSTRAINT chirps_user_id_fkey,
+ALTER COLUMN user_id DROP NOT NULL,
+ADD CONSTRAINT chirps_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL;
+
+-- +goose StatementBegin
+CREATE FUNCTION delete_user_chirps() RETURNS trigger AS $$
+BEGIN
+    DELETE FROM chirps WHERE user_id = OLD.id AND rechirp_of_id IS NOT NULL;
+    -- Deleting one of the account's replies can leave its parent, also the
+    -- account's, with nothing under it.
+    LOOP
+        DELETE FROM chirps
+        WHERE user_id = OLD.id
+        AND deleted_at IS NULL AND reply_count = 0 AND quote_count = 0;
+        EXIT WHEN NOT FOUND;
+    END LOOP;
+    DELETE FROM chirps
+    WHERE rechirp_of_id IN (SELECT id FROM chirps WHERE user_id = OLD.id);
+    UPDATE chirps SET body = '', deleted_at = NOW(), updated_at = NOW()
+    WHERE user_id = OLD.id AND deleted_at IS NULL;
+    RETURN OLD;
+END
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER users_delete_chirps
+BEFORE DELETE ON users
+FOR EACH ROW EXECUTE FUNCTION delete_user_chirps();
+
+-- +goose Down
+DROP TRIGGER users_delete_chirps ON users;
+DROP FUNCTION delete_user_chirps();
+
+DELETE FROM chirps WHERE user_id IS NULL;
+
+ALTER TABLE chirps
+DROP CONSTRAINT chirps_user_id_fkey,
+ALTER COLUMN user_id SET NOT NULL,
+ADD CONSTRAINT chirps_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+DROP TRIGGER chirps_reply_count ON chirps;
+DROP FUNCTION update_chirp_reply_count();
@@ -0,0 +1,55 @@
+-- +goose Up
+-- A tombstone is only kept while it has replies or quotes. The reply
+-- trigger already removes one that loses its last reply; this does the same
+-- when it loses its last quote, deleted or tombstoned.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION update_chirp_share_counts() RETURNS trigger AS $$
+DECLARE
+    quoted UUID;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        UPDATE chirps SET rechirp_count = rechirp_count + 1 WHERE id = NEW.rechirp_of_id;
+        UPDATE chirps SET quote_count = quote_count + 1 WHERE id = NEW.quote_of_id;
+        RETURN NULL;
+    ELSIF TG_OP = 'DELETE' THEN
+        UPDATE chirps SET rechirp_count = rechirp_count - 1 WHERE id = OLD.rechirp_of_id;
+        IF OLD.deleted_at IS NULL THEN
+            quoted := OLD.quote_of_id;
+        END IF;
+    ELSIF OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN
+        quoted := NEW.quote_of_id;
+    END IF;
+
+    IF quoted IS NOT NULL THEN
+        UPDATE chirps SET quote_count = quote_count - 1 WHERE id = quoted;
+        DELETE FROM chirps
+        WHERE id = quoted
+        AND deleted_at IS NOT NULL AND reply_count = 0 AND quote_count = 0;
+    END IF;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+DELETE FROM chirps
+WHERE deleted_at IS NOT NULL AND reply_count = 0 AND quote_count = 0;
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION update_chirp_share_counts() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        UPDATE chirps SET rechirp_count = rechirp_count + 1 WHERE id = NEW.rechirp_of_id;
+        UPDATE chirps SET quote_count = quote_count + 1 WHERE id = NEW.quote_of_id;
+    ELSIF TG_OP = 'DELETE' THEN
+        UPDATE chirps SET rechirp_count = rechirp_count - 1 WHERE id = OLD.rechirp_of_id;
+        IF OLD.deleted_at IS NULL THEN
+            UPDATE chirps SET quote_count = quote_count - 1 WHERE id = OLD.quote_of_id;
+        END IF;
+    ELSIF OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN
+        UPDATE chirps SET quote_count = quote_count - 1 WHERE id = NEW.quote_of_id;
+    END IF;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
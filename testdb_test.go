@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mvusic07/Chirpy/internal/auth"
+	"github.com/mvusic07/Chirpy/internal/database"
+	"github.com/mvusic07/Chirpy/internal/mailer"
+)
+
+// Tests that need Postgres run against the database in CHIRPY_TEST_DB_URL,
+// each in a schema of its own that is migrated up from scratch and dropped
+// afterwards. They are skipped when the variable isn't set.
+const testDBEnv = "CHIRPY_TEST_DB_URL"
+
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dbURL := os.Getenv(testDBEnv)
+	if dbURL == "" {
+		t.Skip(testDBEnv + " is not set")
+	}
+	admin, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatalf("Couldn't open the test database: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	schema := "chirpy_test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatalf("Couldn't create schema: %v", err)
+	}
+	t.Cleanup(func() { admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	u, err := url.Parse(dbURL)
+	if err != nil {
+		t.Fatalf("Couldn't parse %s: %v", testDBEnv, err)
+	}
+	q := u.Query()
+	q.Set("search_path", schema)
+	u.RawQuery = q.Encode()
+	conn, err := sql.Open("postgres", u.String())
+	if err != nil {
+		t.Fatalf("Couldn't open the test schema: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	migrateTestDB(t, conn)
+	return conn
+}
+
+// migrateTestDB runs the Up section of every migration in sql/schema in
+// order. Each one is sent as a single simple query, which lets Postgres
+// split the statements itself, plpgsql bodies included.
+func migrateTestDB(t *testing.T, conn *sql.DB) {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join("sql", "schema", "*.sql"))
+	if err != nil {
+		t.Fatalf("Couldn't list migrations: %v", err)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Couldn't read %s: %v", file, err)
+		}
+		up, _, _ := strings.Cut(string(data), "-- +goose Down")
+		if _, err := conn.Exec(up); err != nil {
+			t.Fatalf("Couldn't apply %s: %v", file, err)
+		}
+	}
+}
+
+// testServer is an apiConfig backed by a fresh test database, with every
+// route registered.
+type testServer struct {
+	*apiConfig
+	handler http.Handler
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	conn := newTestDB(t)
+	db := database.New(conn)
+	keys, err := auth.LoadKeySet(t.TempDir())
+	if err != nil {
+		t.Fatalf("LoadKeySet failed: %v", err)
+	}
+	revocations := newRevocationStore(db)
+	keys.SetRevocationChecker(revocations)
+
+	cfg := &apiConfig{
+		conn:           conn,
+		db:             db,
+		platform:       "dev",
+		keys:           keys,
+		revocations:    revocations,
+		mailer:         &mailer.FileMailer{Dir: t.TempDir(), From: "chirpy@example.com"},
+		exporter:       newDataExporter(db),
+		passwords:      auth.DefaultPasswordHasher,
+		passwordPolicy: auth.DefaultPasswordPolicy,
+		baseURL:        "http://localhost:8080",
+
+		timelinePrecomputeThreshold: defaultTimelinePrecomputeThreshold,
+	}
+	mux := http.NewServeMux()
+	cfg.registerRoutes(mux, ".")
+	return &testServer{apiConfig: cfg, handler: mux}
+}
+
+// do sends a request through the router, with token as the bearer token if
+// set and body encoded as JSON if not nil.
+func (s *testServer) do(t *testing.T, method, target, token string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatalf("Couldn't encode request body: %v", err)
+		}
+	}
+	req := httptest.NewRequest(method, target, &buf)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// decode unmarshals a response body into v, failing the test unless the
+// response has the wanted status.
+func decode(t *testing.T, rec *httptest.ResponseRecorder, status int, v any) {
+	t.Helper()
+	if rec.Code != status {
+		t.Fatalf("Expected status %d, got %d: %s", status, rec.Code, rec.Body)
+	}
+	if v == nil {
+		return
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("Couldn't decode response: %v", err)
+	}
+}
+
+// testSession is a signed-up, logged-in user.
+type testSession struct {
+	User
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+const testPassword = "correct horse battery staple"
+
+// signup creates a verified account and logs it in.
+func (s *testServer) signup(t *testing.T, email string) testSession {
+	t.Helper()
+	var user User
+	decode(t, s.do(t, "POST", "/api/users", "", map[string]string{
+		"email":    email,
+		"password": testPassword,
+	}), http.StatusCreated, &user)
+	if _, err := s.conn.Exec("UPDATE users SET email_verified_at = NOW() WHERE id = $1", user.ID); err != nil {
+		t.Fatalf("Couldn't verify email: %v", err)
+	}
+	return s.login(t, email)
+}
+
+func (s *testServer) login(t *testing.T, email string) testSession {
+	t.Helper()
+	var session testSession
+	decode(t, s.do(t, "POST", "/api/login", "", map[string]string{
+		"email":    email,
+		"password": testPassword,
+	}), http.StatusOK, &session)
+	return session
+}
+
+// personalAccessToken creates a personal access token for session with scopes.
+func (s *testServer) personalAccessToken(t *testing.T, session testSession, scopes ...string) string {
+	t.Helper()
+	var pat struct {
+		Token string `json:"token"`
+	}
+	decode(t, s.do(t, "POST", "/api/tokens", session.Token, map[string]any{
+		"name":   "test",
+		"scopes": scopes,
+	}), http.StatusCreated, &pat)
+	return pat.Token
+}
+
+// postChirp posts a chirp as session with the given request fields.
+func (s *testServer) postChirp(t *testing.T, session testSession, params map[string]any) Chirp {
+	t.Helper()
+	var chirp Chirp
+	decode(t, s.do(t, "POST", "/api/chirps", session.Token, params), http.StatusCreated, &chirp)
+	return chirp
+}
+
+// linkToken returns the token in the link mailed in body.
+func linkToken(t *testing.T, body string) string {
+	t.Helper()
+	_, rest, ok := strings.Cut(body, "?token=")
+	if !ok {
+		t.Fatalf("Expected a link with a token in %q", body)
+	}
+	token, _, _ := strings.Cut(rest, "\n")
+	token, err := url.QueryUnescape(strings.TrimSpace(token))
+	if err != nil {
+		t.Fatalf("Couldn't unescape token: %v", err)
+	}
+	return token
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/mvusic07/Chirpy/internal/database"
+)
+
+// timelineStrategy builds a user's home timeline: their own chirps and those
+// of the accounts they follow, newest first.
+type timelineStrategy interface {
+	Page(ctx context.Context, userID uuid.UUID, cursor pageCursor, limit int32) ([]database.Chirp, error)
+}
+
+// fanOutOnRead merges the followees' chirps at read time. It needs no upkeep
+// and suits most users, whose followees all have few enough followers that
+// nobody's timeline is precomputed.
+type fanOutOnRead struct {
+	db *database.Queries
+}
+
+func (s fanOutOnRead) Page(ctx context.Context, userID uuid.UUID, cursor pageCursor, limit int32) ([]database.Chirp, error) {
+	return s.db.ListTimeline(ctx, database.ListTimelineParams{
+		UserID:          userID,
+		CursorCreatedAt: cursor.CreatedAt,
+		CursorID:        cursor.ID,
+		Limit:           limit,
+	})
+}
+
+// precomputedTimeline reads the chirps of followees who fan out on write from
+// timeline_entries, where each of their chirps is copied for every follower
+// as it is posted, and merges in everyone else's at read time. Accounts are
+// switched to fanning out on write once they have
+// timelinePrecomputeThreshold followers (see handlerFollow), and stay there:
+// their followers would otherwise all merge the same chirps on every read.
+type precomputedTimeline struct {
+	db *database.Queries
+}
+
+// precomputedTimelineBackfill is how many of an author's chirps are copied
+// into a timeline when they start fanning out on write or gain a follower.
+// Older chirps are still on the author's profile.
+const precomputedTimelineBackfill = 1000
+
+func (s precomputedTimeline) Page(ctx context.Context, userID uuid.UUID, cursor pageCursor, limit int32) ([]database.Chirp, error) {
+	return s.db.ListPrecomputedTimeline(ctx, database.ListPrecomputedTimelineParams{
+		UserID:          userID,
+		CursorCreatedAt: cursor.CreatedAt,
+		CursorID:        cursor.ID,
+		Limit:           limit,
+	})
+}
+
+// Enable makes authorID fan out on write, filling their followers' timelines
+// with their latest chirps. The flag is set first, so a chirp posted
+// meanwhile is either fanned out or backfilled.
+func (s precomputedTimeline) Enable(ctx context.Context, authorID uuid.UUID) error {
+	n, err := s.db.EnableFanOutOnWrite(ctx, authorID)
+	if err != nil || n == 0 {
+		return err
+	}
+	return s.db.BackfillFollowerTimelines(ctx, database.BackfillFollowerTimelinesParams{
+		AuthorID: authorID,
+		Limit:    precomputedTimelineBackfill,
+	})
+}
+
+// ChirpPosted copies a new chirp into the timelines of its author's
+// followers, if the author fans out on write.
+func (s precomputedTimeline) ChirpPosted(ctx context.Context, chirp database.Chirp) error {
+	return s.db.FanOutChirp(ctx, database.FanOutChirpParams{
+		ChirpID:   chirp.ID,
+		AuthorID:  chirp.UserID.UUID,
+		CreatedAt: chirp.CreatedAt,
+	})
+}
+
+// Followed adds the followee's recent chirps to the follower's timeline.
+func (s precomputedTimeline) Followed(ctx context.Context, followerID, followeeID uuid.UUID) error {
+	return s.db.BackfillTimeline(ctx, database.BackfillTimelineParams{
+		UserID:   followerID,
+		AuthorID: followeeID,
+		Limit:    precomputedTimelineBackfill,
+	})
+}
+
+// Unfollowed takes the followee's chirps back out of the follower's timeline.
+func (s precomputedTimeline) Unfollowed(ctx context.Context, followerID, followeeID uuid.UUID) error {
+	return s.db.RemoveTimelineAuthor(ctx, database.RemoveTimelineAuthorParams{
+		UserID:   followerID,
+		AuthorID: followeeID,
+	})
+}
+
+// timelineFor picks the strategy for a user's timeline: the precomputed one
+// if they follow anyone who fans out on write, whose chirps are only there.
+func (cfg *apiConfig) timelineFor(ctx context.Context, userID uuid.UUID) (timelineStrategy, error) {
+	precomputed, err := cfg.db.FollowsFanOutAuthor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.timelineStrategy(precomputed), nil
+}
+
+func (cfg *apiConfig) timelineStrategy(precomputed bool) timelineStrategy {
+	if precomputed {
+		return precomputedTimeline{db: cfg.db}
+	}
+	return fanOutOnRead{db: cfg.db}
+}
+
+// chirpPosted keeps precomputed timelines up to date with a new chirp. It
+// only logs failures: the chirp is already posted, and a gap in some
+// timelines is better than an error for the author.
+func (cfg *apiConfig) chirpPosted(ctx context.Context, chirp database.Chirp) {
+	if err := (precomputedTimeline{db: cfg.db}).ChirpPosted(ctx, chirp); err != nil {
+		log.Printf("Couldn't add chirp %s to timelines: %s", chirp.ID, err)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestTimelineStrategy(t *testing.T) {
+	cfg := &apiConfig{}
+	if _, ok := cfg.timelineStrategy(false).(fanOutOnRead); !ok {
+		t.Error("Expected users to read their timeline on demand by default")
+	}
+	if _, ok := cfg.timelineStrategy(true).(precomputedTimeline); !ok {
+		t.Error("Expected a precomputed timeline for followers of accounts that fan out on write")
+	}
+}
+
+func TestTimeline_StrategiesAgree(t *testing.T) {
+	s := newTestServer(t)
+	s.timelinePrecomputeThreshold = 2
+	reader := s.signup(t, "kim@example.com")
+	popular := s.signup(t, "saul@example.com")
+	normal := s.signup(t, "howard@example.com")
+	other := s.signup(t, "chuck@example.com")
+
+	post := func(session testSession, body string, inReplyTo *uuid.UUID) Chirp {
+		t.Helper()
+		var chirp Chirp
+		decode(t, s.do(t, "POST", "/api/chirps", session.Token, map[string]any{
+			"body":           body,
+			"in_reply_to_id": inReplyTo,
+		}), http.StatusCreated, &chirp)
+		return chirp
+	}
+	follow := func(follower, followee testSession) {
+		t.Helper()
+		decode(t, s.do(t, "POST", "/api/users/"+followee.ID.String()+"/follow", follower.Token, nil), http.StatusNoContent, nil)
+	}
+	// compare checks that both strategies, and GET /api/timeline, return
+	// the reader's timeline the same way.
+	compare := func(step string, wantPrecomputed bool) {
+		t.Helper()
+		ctx := context.Background()
+		cursor := firstPageCursor(true)
+		onRead, err := fanOutOnRead{db: s.db}.Page(ctx, reader.ID, cursor, 100)
+		if err != nil {
+			t.Fatalf("%s: fan-out on read failed: %v", step, err)
+		}
+		precomputed, err := precomputedTimeline{db: s.db}.Page(ctx, reader.ID, cursor, 100)
+		if err != nil {
+			t.Fatalf("%s: precomputed timeline failed: %v", step, err)
+		}
+		var served []Chirp
+		decode(t, s.do(t, "GET", "/api/timeline", reader.Token, nil), http.StatusOK, &served)
+
+		want := make([]uuid.UUID, len(onRead))
+		for i, chirp := range onRead {
+			want[i] = chirp.ID
+		}
+		got := make([]uuid.UUID, len(precomputed))
+		for i, chirp := range precomputed {
+			got[i] = chirp.ID
+		}
+		gotServed := make([]uuid.UUID, len(served))
+		for i, chirp := range served {
+			gotServed[i] = chirp.ID
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: expected the precomputed timeline %v to match fan-out on read %v", step, got, want)
+		}
+		if !slices.Equal(gotServed, want) {
+			t.Errorf("%s: expected GET /api/timeline %v to match fan-out on read %v", step, gotServed, want)
+		}
+
+		strategy, err := s.timelineFor(ctx, reader.ID)
+		if err != nil {
+			t.Fatalf("%s: timelineFor failed: %v", step, err)
+		}
+		if _, ok := strategy.(precomputedTimeline); ok != wantPrecomputed {
+			t.Errorf("%s: expected precomputed %v, got %T", step, wantPrecomputed, strategy)
+		}
+	}
+
+	follow(reader, normal)
+	post(normal, "normal 1", nil)
+	post(reader, "mine", nil)
+	early := post(popular, "popular 1", nil)
+	follow(reader, popular)
+	compare("before fan-out", false)
+
+	// A second follower switches the popular account to fanning out,
+	// backfilling its earlier chirps.
+	follow(other, popular)
+	compare("after fan-out starts", true)
+
+	later := post(popular, "popular 2", nil)
+	post(normal, "normal 2", nil)
+	compare("after posting", true)
+
+	decode(t, s.do(t, "DELETE", "/api/chirps/"+early.ID.String(), popular.Token, nil), http.StatusNoContent, nil)
+	compare("after delete", true)
+
+	// A chirp with replies is tombstoned rather than deleted.
+	post(normal, "reply", &later.ID)
+	decode(t, s.do(t, "DELETE", "/api/chirps/"+later.ID.String(), popular.Token, nil), http.StatusNoContent, nil)
+	compare("after tombstone", true)
+
+	post(popular, "popular 3", nil)
+	decode(t, s.do(t, "DELETE", "/api/users/"+popular.ID.String()+"/follow", reader.Token, nil), http.StatusNoContent, nil)
+	compare("after unfollow", false)
+
+	follow(reader, popular)
+	compare("after following again", true)
+}